@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fireharp/cursor-rules/pkg/manager"
 	"github.com/fireharp/cursor-rules/pkg/templates"
@@ -20,13 +23,20 @@ var (
 	date    = "unknown"
 )
 
+// exitOnError exits the process, releasing the project lock first once it is held.
+var exitOnError = os.Exit
+
 // AppFlagSets contains all the flag sets for subcommands.
 type AppFlagSets struct {
 	addCmd                 *flag.FlagSet
 	addRefCmd              *flag.FlagSet
 	removeCmd              *flag.FlagSet
 	upgradeCmd             *flag.FlagSet
+	upgradeFlags           upgradeFlags
 	updateCmd              *flag.FlagSet
+	updateFlags            upgradeFlags
+	outdatedCmd            *flag.FlagSet
+	outdatedJSONFlag       *bool
 	listCmd                *flag.FlagSet
 	listDetailedFlag       *bool
 	lockLocationCmd        *flag.FlagSet
@@ -36,6 +46,34 @@ type AppFlagSets struct {
 	shareEmbedFlag         *bool
 	restoreCmd             *flag.FlagSet
 	restoreAutoResolveFlag *string
+	installCmd             *flag.FlagSet
+	installFrozenFlag      *bool
+	lockCmd                *flag.FlagSet
+	verifyCmd              *flag.FlagSet
+	verifyJSONFlag         *bool
+	statusCmd              *flag.FlagSet
+	statusLocalFlag        *bool
+	adoptCmd               *flag.FlagSet
+	diffCmd                *flag.FlagSet
+	resetCmd               *flag.FlagSet
+	resolveCmd             *flag.FlagSet
+}
+
+// upgradeFlags holds the flags shared by the 'upgrade' and 'update' commands.
+type upgradeFlags struct {
+	all            *bool
+	onLocalChanges *string
+	unpin          *bool
+}
+
+// defineUpgradeFlags adds the 'upgrade' flags to a flag set.
+func defineUpgradeFlags(cmd *flag.FlagSet) upgradeFlags {
+	return upgradeFlags{
+		all: cmd.Bool("all", false, "Upgrade every outdated rule"),
+		onLocalChanges: cmd.String("on-local-changes", "",
+			"How to handle locally edited rule files: overwrite, keep or merge (default: merge, asking if needed)"),
+		unpin: cmd.Bool("unpin", false, "Unpin rules pinned to a commit without asking"),
+	}
 }
 
 func main() {
@@ -44,6 +82,16 @@ func main() {
 	initFlag := flag.Bool("init", false, "Initialize Cursor Rules with just the init template")
 	setupFlag := flag.Bool("setup", false, "Run project type detection and setup appropriate rules")
 	debugFlag := flag.Bool("debug", false, "Enable debug output")
+	yesFlag := flag.Bool("yes", false, "Answer yes to every confirmation prompt")
+	noInputFlag := flag.Bool("no-input", false, "Never prompt; fail when a question has no answer from a flag")
+	rateLimitWaitFlag := flag.Duration("rate-limit-wait", 0,
+		"How long to wait for a GitHub rate limit to reset before retrying (0 fails immediately)")
+	offlineFlag := flag.Bool("offline", false,
+		"Use only the local cache, never the network (also "+manager.OfflineEnv+"=1)")
+	concurrencyFlag := flag.Int("concurrency", manager.DefaultConcurrency,
+		"How many rules to download at once when installing several")
+	lockTimeoutFlag := flag.Duration("lock-timeout", 30*time.Second,
+		"How long to wait for another cursor-rules process in this project (0 fails immediately)")
 
 	// Parse flags
 	flag.Parse()
@@ -60,6 +108,18 @@ func main() {
 		fmt.Println("Debug mode enabled")
 	}
 
+	// Without a terminal, prompts fail with a hint at the flag that answers them
+	manager.Prompts.AssumeYes = *yesFlag
+	manager.Prompts.NoInput = *noInputFlag
+	manager.RateLimitWait = *rateLimitWaitFlag
+	manager.Offline = *offlineFlag
+
+	if *concurrencyFlag < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --concurrency must be at least 1, got %d\n", *concurrencyFlag)
+		os.Exit(1)
+	}
+	manager.Concurrency = *concurrencyFlag
+
 	// Get command if present
 	command := ""
 	if len(args) > 0 {
@@ -72,7 +132,8 @@ func main() {
 		return
 	}
 
-	fmt.Println("Cursor Rules Initializer")
+	// Banners go to stderr so that machine-readable output (--json) stays parseable
+	fmt.Fprintln(os.Stderr, "Cursor Rules Initializer")
 
 	// Initialize environment (directories, templates)
 	cwd, cursorDir, _, err := initializeEnvironment()
@@ -81,13 +142,46 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Read the project config up front so a broken config is reported before any command runs
+	if _, err := manager.LoadProjectConfig(cursorDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The user config only matters to commands that download something; the others
+	// report a broken one and carry on with the defaults
+	ctx, err := loadUserConfig(context.Background())
+	if err != nil {
+		if commandNeedsGitHub(command) {
+			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: ignoring user config: %v\n", err)
+	}
+
+	// Hold the project lock for the whole command so concurrent invocations
+	// can't interleave their lockfile updates
+	if len(args) > 0 || *initFlag || *setupFlag {
+		projectLock, err := manager.AcquireProjectLock(cursorDir, *lockTimeoutFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Lock error: %v\n", err)
+			os.Exit(1)
+		}
+		defer projectLock.Release()
+
+		exitOnError = func(code int) {
+			projectLock.Release()
+			os.Exit(code)
+		}
+	}
+
 	// Handle command-line style commands
 	if len(args) > 0 {
 		// Handle subcommands
-		handled, err := handleCommand(cursorDir, args[0], args[1:], flagSets)
+		handled, err := handleCommand(ctx, cursorDir, args[0], args[1:], flagSets)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Command error: %v\n", err)
-			os.Exit(1)
+			printCommandError(err)
+			exitOnError(1)
 		}
 		if handled {
 			return
@@ -109,6 +203,30 @@ func main() {
 	showHelp()
 }
 
+// printCommandError prints the error a command failed with, explaining GitHub rate limits
+// and offline cache misses.
+func printCommandError(err error) {
+	fmt.Fprintf(os.Stderr, "Command error: %v\n", err)
+
+	if manager.IsOfflineCacheMissError(err) {
+		fmt.Fprintln(os.Stderr, "\nRun the command once with network access to fill the cache, or drop --offline.")
+		return
+	}
+
+	var rateLimitErr *manager.ErrGitHubRateLimit
+	if !errors.As(err, &rateLimitErr) {
+		return
+	}
+
+	reset := rateLimitErr.Reset
+	fmt.Fprintf(os.Stderr, "\nThe GitHub API rate limit was reached. It resets at %s (in %s).\n",
+		reset.Local().Format(time.Kitchen), time.Until(reset).Round(time.Second))
+	if !rateLimitErr.Authenticated {
+		fmt.Fprintln(os.Stderr, "Set GITHUB_TOKEN to raise the limit from 60 to 5,000 requests per hour.")
+	}
+	fmt.Fprintln(os.Stderr, "Use --rate-limit-wait=DURATION to wait for the reset and retry automatically.")
+}
+
 // defineFlagSets sets up flag sets for subcommands.
 func defineFlagSets() AppFlagSets {
 	// Define flag sets for subcommands
@@ -116,7 +234,12 @@ func defineFlagSets() AppFlagSets {
 	addRefCmd := flag.NewFlagSet("add-ref", flag.ExitOnError)
 	removeCmd := flag.NewFlagSet("remove", flag.ExitOnError)
 	upgradeCmd := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	upgradeFlags := defineUpgradeFlags(upgradeCmd)
 	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
+	updateFlags := defineUpgradeFlags(updateCmd)
+
+	outdatedCmd := flag.NewFlagSet("outdated", flag.ExitOnError)
+	outdatedJSONFlag := outdatedCmd.Bool("json", false, "Print the outdated rules as JSON")
 
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 	listDetailedFlag := listCmd.Bool("detailed", false, "Show detailed information about installed rules")
@@ -135,12 +258,33 @@ func defineFlagSets() AppFlagSets {
 	restoreAutoResolveFlag := restoreCmd.String("auto-resolve", "",
 		"Automatically resolve conflicts (options: skip, overwrite, rename)")
 
+	installCmd := flag.NewFlagSet("install", flag.ExitOnError)
+	installFrozenFlag := installCmd.Bool("frozen", false,
+		"Reinstall exactly what the lockfile records and fail if it would change")
+
+	lockCmd := flag.NewFlagSet("lock", flag.ExitOnError)
+
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyJSONFlag := verifyCmd.Bool("json", false, "Print the verification report as JSON")
+
+	statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
+	statusLocalFlag := statusCmd.Bool("local", false, "Only compare with the lockfile, don't check upstream sources")
+
+	adoptCmd := flag.NewFlagSet("adopt", flag.ExitOnError)
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	resetCmd := flag.NewFlagSet("reset", flag.ExitOnError)
+	resolveCmd := flag.NewFlagSet("resolve", flag.ExitOnError)
+
 	return AppFlagSets{
 		addCmd:                 addCmd,
 		addRefCmd:              addRefCmd,
 		removeCmd:              removeCmd,
 		upgradeCmd:             upgradeCmd,
+		upgradeFlags:           upgradeFlags,
 		updateCmd:              updateCmd,
+		updateFlags:            updateFlags,
+		outdatedCmd:            outdatedCmd,
+		outdatedJSONFlag:       outdatedJSONFlag,
 		listCmd:                listCmd,
 		listDetailedFlag:       listDetailedFlag,
 		lockLocationCmd:        lockLocationCmd,
@@ -150,6 +294,17 @@ func defineFlagSets() AppFlagSets {
 		shareEmbedFlag:         shareEmbedFlag,
 		restoreCmd:             restoreCmd,
 		restoreAutoResolveFlag: restoreAutoResolveFlag,
+		installCmd:             installCmd,
+		installFrozenFlag:      installFrozenFlag,
+		lockCmd:                lockCmd,
+		verifyCmd:              verifyCmd,
+		verifyJSONFlag:         verifyJSONFlag,
+		statusCmd:              statusCmd,
+		statusLocalFlag:        statusLocalFlag,
+		adoptCmd:               adoptCmd,
+		diffCmd:                diffCmd,
+		resetCmd:               resetCmd,
+		resolveCmd:             resolveCmd,
 	}
 }
 
@@ -199,7 +354,7 @@ func initializeEnvironment() (cwd, cursorDir, projectDir string, err error) {
 		return "", "", "", fmt.Errorf("error creating directory: %w", err)
 	}
 
-	fmt.Printf("Initialized .cursor/rules directory in %s\n", cursorDir)
+	fmt.Fprintf(os.Stderr, "Initialized .cursor/rules directory in %s\n", cursorDir)
 	return cwd, cursorDir, projectDir, nil
 }
 
@@ -251,24 +406,52 @@ func createBasicTemplates() {
 	}
 }
 
+// loadUserConfig applies the GitHub hosts and download settings of the user config, and
+// returns ctx with the HTTP client configured there. On error ctx is returned unchanged,
+// so requests use the default client.
+func loadUserConfig(ctx context.Context) (context.Context, error) {
+	if err := manager.LoadGitHubHosts(); err != nil {
+		return ctx, err
+	}
+	if err := manager.LoadDownloadConfig(); err != nil {
+		return ctx, err
+	}
+	client, err := manager.LoadHTTPClient()
+	if err != nil {
+		return ctx, err
+	}
+	return manager.WithHTTPClient(ctx, client), nil
+}
+
+// commandNeedsGitHub reports whether a command may download rules or query GitHub.
+func commandNeedsGitHub(command string) bool {
+	switch command {
+	case "add", "add-ref", "upgrade", "update", "outdated", "restore", "status", "diff", "reset", "install":
+		return true
+	}
+	return false
+}
+
 // printVersion prints the version information.
 func printVersion() {
 	fmt.Printf("cursor-rules version %s, commit %s, built at %s\n", version, commit, date)
 }
 
 // handleCommand processes the given command and its arguments.
-func handleCommand(cursorDir, command string, args []string, flagSets AppFlagSets) (bool, error) {
+func handleCommand(ctx context.Context, cursorDir, command string, args []string, flagSets AppFlagSets) (bool, error) {
 	switch command {
 	case "add":
-		return true, handleAddCommand(cursorDir, args, flagSets.addCmd)
+		return true, handleAddCommand(ctx, cursorDir, args, flagSets.addCmd)
 	case "add-ref":
-		return true, handleAddRefCommand(cursorDir, args, flagSets.addRefCmd)
+		return true, handleAddRefCommand(ctx, cursorDir, args, flagSets.addRefCmd)
 	case "remove":
 		return true, handleRemoveCommand(cursorDir, args, flagSets.removeCmd)
 	case "upgrade":
-		return true, handleUpgradeCommand(cursorDir, args, flagSets.upgradeCmd)
+		return true, handleUpgradeCommand(ctx, cursorDir, args, flagSets.upgradeCmd, flagSets.upgradeFlags)
 	case "update":
-		return true, handleUpdateCommand(cursorDir, args, flagSets.updateCmd)
+		return true, handleUpdateCommand(ctx, cursorDir, args, flagSets.updateCmd, flagSets.updateFlags)
+	case "outdated":
+		return true, handleOutdatedCommand(ctx, cursorDir, args, flagSets.outdatedCmd, flagSets.outdatedJSONFlag)
 	case "list":
 		return true, handleListCommand(cursorDir, args, flagSets.listCmd, flagSets.listDetailedFlag)
 	case "set-lock-location":
@@ -276,7 +459,23 @@ func handleCommand(cursorDir, command string, args []string, flagSets AppFlagSet
 	case "share":
 		return true, handleShareCommand(cursorDir, args, flagSets.shareCmd, flagSets.shareOutputFlag, flagSets.shareEmbedFlag)
 	case "restore":
-		return true, handleRestoreCommand(cursorDir, args, flagSets.restoreCmd, flagSets.restoreAutoResolveFlag)
+		return true, handleRestoreCommand(ctx, cursorDir, args, flagSets.restoreCmd, flagSets.restoreAutoResolveFlag)
+	case "lock":
+		return true, handleLockCommand(cursorDir, args, flagSets.lockCmd)
+	case "verify":
+		return true, handleVerifyCommand(cursorDir, args, flagSets.verifyCmd, flagSets.verifyJSONFlag)
+	case "status":
+		return true, handleStatusCommand(ctx, cursorDir, args, flagSets.statusCmd, flagSets.statusLocalFlag)
+	case "adopt":
+		return true, handleAdoptCommand(cursorDir, args, flagSets.adoptCmd)
+	case "diff":
+		return true, handleDiffCommand(ctx, cursorDir, args, flagSets.diffCmd)
+	case "reset":
+		return true, handleResetCommand(ctx, cursorDir, args, flagSets.resetCmd)
+	case "resolve":
+		return true, handleResolveCommand(cursorDir, args, flagSets.resolveCmd)
+	case "install":
+		return true, handleInstallCommand(ctx, cursorDir, args, flagSets.installCmd, flagSets.installFrozenFlag)
 	case "init":
 		runInitCommand(cursorDir)
 		return true, nil
@@ -288,7 +487,7 @@ func handleCommand(cursorDir, command string, args []string, flagSets AppFlagSet
 }
 
 // Handler for the 'add' command.
-func handleAddCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+func handleAddCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing add command: %w", err)
 	}
@@ -302,21 +501,17 @@ func handleAddCommand(cursorDir string, args []string, cmd *flag.FlagSet) error
 		return nil
 	}
 
-	// Process all references provided
-	for i := 0; i < cmd.NArg(); i++ {
-		reference := cmd.Arg(i)
-		err := manager.AddRuleByReference(cursorDir, reference)
-		if err != nil {
-			return fmt.Errorf("error adding rule from reference %q: %w", reference, err)
-		}
-		fmt.Printf("Rule from %q added successfully\n", reference)
+	// Download all references in parallel, recording them with one lockfile write
+	if err := manager.AddRulesByReference(ctx, cursorDir, cmd.Args()); err != nil {
+		return err
 	}
 
+	fmt.Printf("Added %d reference(s) successfully\n", cmd.NArg())
 	return nil
 }
 
 // Handler for the 'add-ref' command.
-func handleAddRefCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+func handleAddRefCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing add-ref command: %w", err)
 	}
@@ -330,15 +525,12 @@ func handleAddRefCommand(cursorDir string, args []string, cmd *flag.FlagSet) err
 		return nil
 	}
 
-	// Process all references provided
-	for i := 0; i < cmd.NArg(); i++ {
-		reference := cmd.Arg(i)
-		if err := manager.AddRuleByReference(cursorDir, reference); err != nil {
-			return fmt.Errorf("error adding rule from reference %q: %w", reference, err)
-		}
-		fmt.Printf("Rule from %q added successfully\n", reference)
+	// Download all references in parallel, recording them with one lockfile write
+	if err := manager.AddRulesByReference(ctx, cursorDir, cmd.Args()); err != nil {
+		return err
 	}
 
+	fmt.Printf("Added %d reference(s) successfully\n", cmd.NArg())
 	return nil
 }
 
@@ -363,18 +555,27 @@ func handleRemoveCommand(cursorDir string, args []string, cmd *flag.FlagSet) err
 }
 
 // Handler for the 'upgrade' command.
-func handleUpgradeCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+func handleUpgradeCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, flags upgradeFlags) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing upgrade command: %w", err)
 	}
 
+	if err := applyUpgradeFlags(flags); err != nil {
+		return err
+	}
+
+	if *flags.all {
+		return upgradeAll(ctx, cursorDir)
+	}
+
 	if cmd.NArg() < 1 {
 		fmt.Println("Usage: cursor-rules upgrade <ruleKey>")
+		fmt.Println("       cursor-rules upgrade --all")
 		return nil
 	}
 
 	ruleKey := cmd.Arg(0)
-	if err := manager.UpgradeRule(cursorDir, ruleKey); err != nil {
+	if err := manager.UpgradeRuleContext(ctx, cursorDir, ruleKey); err != nil {
 		return fmt.Errorf("error upgrading rule: %w", err)
 	}
 
@@ -383,19 +584,28 @@ func handleUpgradeCommand(cursorDir string, args []string, cmd *flag.FlagSet) er
 }
 
 // Handler for the 'update' command (alias for upgrade).
-func handleUpdateCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+func handleUpdateCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, flags upgradeFlags) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing update command: %w", err)
 	}
 
+	if err := applyUpgradeFlags(flags); err != nil {
+		return err
+	}
+
+	if *flags.all {
+		return upgradeAll(ctx, cursorDir)
+	}
+
 	if cmd.NArg() < 1 {
 		fmt.Println("Usage: cursor-rules update <ruleKey>")
+		fmt.Println("       cursor-rules update --all")
 		fmt.Println("  (This is an alias for 'upgrade')")
 		return nil
 	}
 
 	ruleKey := cmd.Arg(0)
-	if err := manager.UpgradeRule(cursorDir, ruleKey); err != nil {
+	if err := manager.UpgradeRuleContext(ctx, cursorDir, ruleKey); err != nil {
 		return fmt.Errorf("error updating rule: %w", err)
 	}
 
@@ -403,23 +613,99 @@ func handleUpdateCommand(cursorDir string, args []string, cmd *flag.FlagSet) err
 	return nil
 }
 
+// applyUpgradeFlags sets the prompt answers given on the command line.
+func applyUpgradeFlags(flags upgradeFlags) error {
+	if err := manager.ValidateLocalChangesPolicy(*flags.onLocalChanges); err != nil {
+		return fmt.Errorf("invalid --on-local-changes: %w", err)
+	}
+	manager.Prompts.OnLocalChanges = *flags.onLocalChanges
+	manager.Prompts.Unpin = *flags.unpin
+	return nil
+}
+
+// upgradeAll upgrades every outdated rule for 'upgrade --all' and 'update --all'.
+func upgradeAll(ctx context.Context, cursorDir string) error {
+	upgraded, err := manager.UpgradeAllRules(ctx, cursorDir)
+	if len(upgraded) > 0 {
+		fmt.Printf("Upgraded %d rule(s): %s\n", len(upgraded), strings.Join(upgraded, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("error upgrading rules: %w", err)
+	}
+	if len(upgraded) == 0 {
+		fmt.Println("All rules are up to date.")
+	}
+	return nil
+}
+
+// Handler for the 'outdated' command.
+func handleOutdatedCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, jsonFlag *bool) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing outdated command: %w", err)
+	}
+
+	report, err := manager.FindOutdatedRules(ctx, cursorDir)
+	if err != nil {
+		return fmt.Errorf("error checking for outdated rules: %w", err)
+	}
+
+	if *jsonFlag {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error serializing report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, row := range report.Rules {
+			fmt.Printf("  %-30s %s -> %s\n", row.RuleKey, row.Current, row.Latest)
+		}
+
+		failedKeys := make([]string, 0, len(report.Errors))
+		for key := range report.Errors {
+			failedKeys = append(failedKeys, key)
+		}
+		sort.Strings(failedKeys)
+		for _, key := range failedKeys {
+			fmt.Printf("Warning: could not check %s for updates: %s\n", key, report.Errors[key])
+		}
+
+		if len(report.Rules) == 0 {
+			fmt.Println("All rules are up to date.")
+		} else {
+			fmt.Println("Run 'cursor-rules upgrade --all' to upgrade them.")
+		}
+	}
+
+	// A non-zero exit lets CI fail when rules are behind
+	if len(report.Rules) > 0 {
+		return fmt.Errorf("%d rule(s) are outdated", len(report.Rules))
+	}
+	return nil
+}
+
 // Handler for the 'list' command.
 func handleListCommand(cursorDir string, args []string, cmd *flag.FlagSet, detailedFlag *bool) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing list command: %w", err)
 	}
 
-	// First sync any local rules that aren't in the lockfile
-	err := manager.SyncLocalRules(cursorDir)
+	var err error
+	if *detailedFlag {
+		err = showDetailedList(cursorDir)
+	} else {
+		err = showSimpleList(cursorDir)
+	}
 	if err != nil {
-		fmt.Printf("Error syncing local rules: %v\n", err)
-		// Continue anyway to show what's in the lockfile
+		return err
 	}
 
-	if *detailedFlag {
-		return showDetailedList(cursorDir)
+	// Listing never modifies the lockfile, point at 'adopt' for files it doesn't know about
+	if report, err := manager.VerifyRules(cursorDir); err == nil {
+		if n := report.Count(manager.FileStatusUntracked); n > 0 {
+			fmt.Printf("\n%d untracked rule file(s) in .cursor/rules, run 'cursor-rules adopt' to track them.\n", n)
+		}
 	}
-	return showSimpleList(cursorDir)
+	return nil
 }
 
 // Shows a detailed list of installed rules.
@@ -445,6 +731,9 @@ func showDetailedList(cursorDir string) error {
 		if len(r.LocalFiles) > 0 {
 			fmt.Printf("    Files: %s\n", strings.Join(r.LocalFiles, ", "))
 		}
+		if r.MergeConflict {
+			fmt.Printf("    Status: unresolved merge conflict (run 'cursor-rules resolve %s' once fixed)\n", r.Key)
+		}
 		fmt.Println()
 	}
 	return nil
@@ -513,7 +802,7 @@ func handleShareCommand(cursorDir string, args []string, cmd *flag.FlagSet, outp
 }
 
 // Handler for the 'restore' command.
-func handleRestoreCommand(cursorDir string, args []string, cmd *flag.FlagSet, autoResolveFlag *string) error {
+func handleRestoreCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, autoResolveFlag *string) error {
 	if err := cmd.Parse(args); err != nil {
 		return fmt.Errorf("error parsing restore command: %w", err)
 	}
@@ -533,7 +822,7 @@ func handleRestoreCommand(cursorDir string, args []string, cmd *flag.FlagSet, au
 		return nil
 	}
 
-	if err := manager.RestoreFromShared(context.Background(), cursorDir, sharedFilePath, autoResolve); err != nil {
+	if err := manager.RestoreFromShared(ctx, cursorDir, sharedFilePath, autoResolve); err != nil {
 		return fmt.Errorf("error restoring rules: %w", err)
 	}
 
@@ -541,6 +830,242 @@ func handleRestoreCommand(cursorDir string, args []string, cmd *flag.FlagSet, au
 	return nil
 }
 
+// Handler for the 'install' command.
+func handleInstallCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, frozenFlag *bool) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing install command: %w", err)
+	}
+
+	if *frozenFlag {
+		result, err := manager.InstallFrozen(ctx, cursorDir)
+		if err != nil {
+			return fmt.Errorf("error installing rules from lockfile: %w", err)
+		}
+		fmt.Printf("Installed %d rules from the lockfile (%d kept as-is)\n",
+			len(result.Added), len(result.Unchanged))
+		return nil
+	}
+
+	result, err := manager.Install(ctx, cursorDir)
+	if err != nil {
+		return fmt.Errorf("error installing rules: %w", err)
+	}
+
+	for _, ref := range result.Added {
+		fmt.Printf("  + %s\n", ref)
+	}
+	for _, key := range result.Removed {
+		fmt.Printf("  - %s\n", key)
+	}
+
+	fmt.Printf("Installed %d, removed %d, unchanged %d\n",
+		len(result.Added), len(result.Removed), len(result.Unchanged))
+	return nil
+}
+
+// Handler for the 'lock' command.
+func handleLockCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing lock command: %w", err)
+	}
+
+	if cmd.NArg() < 1 || cmd.Arg(0) != "migrate" {
+		fmt.Println("Usage: cursor-rules lock migrate")
+		fmt.Println("  Rewrites the lockfile in the current schema version")
+		return nil
+	}
+
+	fromVersion, err := manager.MigrateLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("error migrating lockfile: %w", err)
+	}
+
+	if fromVersion == manager.CurrentLockFileVersion {
+		fmt.Printf("Lockfile is already at version %d\n", fromVersion)
+		return nil
+	}
+	fmt.Printf("Lockfile migrated from version %d to %d\n", fromVersion, manager.CurrentLockFileVersion)
+	return nil
+}
+
+// Handler for the 'verify' command.
+func handleVerifyCommand(cursorDir string, args []string, cmd *flag.FlagSet, jsonFlag *bool) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing verify command: %w", err)
+	}
+
+	report, err := manager.VerifyRules(cursorDir)
+	if err != nil {
+		return fmt.Errorf("error verifying rules: %w", err)
+	}
+
+	if *jsonFlag {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error serializing report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, f := range report.Files {
+			line := fmt.Sprintf("  %-11s %s", f.Status, f.Path)
+			if f.RuleKey != "" {
+				line += fmt.Sprintf(" (%s)", f.RuleKey)
+			}
+			if f.Status == manager.FileStatusUnverified {
+				line += " - no hash recorded"
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("%d ok, %d modified, %d missing, %d untracked, %d without hash\n",
+			report.Count(manager.FileStatusOK), report.Count(manager.FileStatusModified),
+			report.Count(manager.FileStatusMissing), report.Count(manager.FileStatusUntracked),
+			report.Count(manager.FileStatusUnverified))
+	}
+
+	if report.HasDrift() {
+		return errors.New("rule files have drifted from the lockfile")
+	}
+	return nil
+}
+
+// Handler for the 'status' command.
+func handleStatusCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet, localFlag *bool) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing status command: %w", err)
+	}
+
+	report, err := manager.GetStatus(ctx, cursorDir, !*localFlag)
+	if err != nil {
+		return fmt.Errorf("error getting status: %w", err)
+	}
+
+	printSection := func(title, label string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Println(title)
+		for _, item := range items {
+			if label != "" {
+				fmt.Printf("  %-10s %s\n", label, item)
+			} else {
+				fmt.Printf("  %s\n", item)
+			}
+		}
+		fmt.Println()
+	}
+
+	printSection("Rules modified locally:", "modified:", report.Modified)
+	printSection("Rules deleted from disk:", "deleted:", report.Deleted)
+	printSection("Rules with unresolved merge conflicts:", "conflict:", report.Unresolved)
+	printSection("Untracked rule files:", "", report.Untracked)
+	printSection("Rules with upstream changes:", "upstream:", report.UpstreamChanged)
+
+	failedKeys := make([]string, 0, len(report.UpstreamErrors))
+	for key := range report.UpstreamErrors {
+		failedKeys = append(failedKeys, key)
+	}
+	sort.Strings(failedKeys)
+	for _, key := range failedKeys {
+		fmt.Printf("Warning: could not check upstream for %s: %s\n", key, report.UpstreamErrors[key])
+	}
+
+	if report.IsClean() {
+		fmt.Printf("%d rule(s), all match the lockfile.\n", len(report.Clean))
+	} else {
+		fmt.Printf("%d clean, %d modified, %d deleted, %d untracked\n",
+			len(report.Clean), len(report.Modified), len(report.Deleted), len(report.Untracked))
+	}
+	return nil
+}
+
+// Handler for the 'adopt' command.
+func handleAdoptCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing adopt command: %w", err)
+	}
+
+	adopted, err := manager.AdoptRules(cursorDir, cmd.Args())
+	if err != nil {
+		return fmt.Errorf("error adopting rules: %w", err)
+	}
+
+	if len(adopted) == 0 {
+		fmt.Println("No untracked rule files to adopt.")
+		return nil
+	}
+
+	for _, rule := range adopted {
+		if rule.SourceType == manager.SourceTypeUnmanaged {
+			fmt.Printf("Adopted %s as %s (unmanaged)\n", rule.LocalFiles[0], rule.Key)
+		} else {
+			fmt.Printf("Adopted %s as %s (matches %s %s)\n", rule.LocalFiles[0], rule.Key, rule.SourceType, rule.Reference)
+		}
+	}
+	return nil
+}
+
+// Handler for the 'diff' command.
+func handleDiffCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing diff command: %w", err)
+	}
+
+	if cmd.NArg() < 1 {
+		return errors.New("missing rule key. Usage: cursor-rules diff <ruleKey>")
+	}
+
+	ruleKey := cmd.Arg(0)
+	diff, err := manager.DiffRule(ctx, cursorDir, ruleKey)
+	if err != nil {
+		return fmt.Errorf("error diffing rule: %w", err)
+	}
+
+	if diff == "" {
+		fmt.Fprintf(os.Stderr, "No local changes to %s.\n", ruleKey)
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// Handler for the 'reset' command.
+func handleResetCommand(ctx context.Context, cursorDir string, args []string, cmd *flag.FlagSet) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing reset command: %w", err)
+	}
+
+	if cmd.NArg() < 1 {
+		return errors.New("missing rule key. Usage: cursor-rules reset <ruleKey>")
+	}
+
+	ruleKey := cmd.Arg(0)
+	if err := manager.ResetRule(ctx, cursorDir, ruleKey); err != nil {
+		return fmt.Errorf("error resetting rule: %w", err)
+	}
+
+	fmt.Printf("Rule %q restored to its installed version.\n", ruleKey)
+	return nil
+}
+
+// Handler for the 'resolve' command.
+func handleResolveCommand(cursorDir string, args []string, cmd *flag.FlagSet) error {
+	if err := cmd.Parse(args); err != nil {
+		return fmt.Errorf("error parsing resolve command: %w", err)
+	}
+
+	if cmd.NArg() < 1 {
+		return errors.New("missing rule key. Usage: cursor-rules resolve <ruleKey>")
+	}
+
+	ruleKey := cmd.Arg(0)
+	if err := manager.ResolveRule(cursorDir, ruleKey); err != nil {
+		return fmt.Errorf("error resolving rule: %w", err)
+	}
+
+	fmt.Printf("Rule %q marked as resolved.\n", ruleKey)
+	return nil
+}
+
 // Show help information for the cursor-rules command.
 func showHelp() {
 	fmt.Println("Usage: cursor-rules [command]")
@@ -550,10 +1075,21 @@ func showHelp() {
 	fmt.Println("  add <reference> [<ref2> ...]   Add rule(s) from reference(s) (local file or GitHub URL)")
 	fmt.Println("  add-ref <reference> [<ref2> ...] (Alias for 'add') Add rule(s) using direct reference(s)")
 	fmt.Println("  remove <ruleKey>               Remove an installed rule")
-	fmt.Println("  upgrade <ruleKey>              Upgrade a rule to the latest version")
-	fmt.Println("  update <ruleKey>               (Alias for 'upgrade') Update a rule to the latest version")
+	fmt.Println("  install [--frozen]             Install the rules listed in cursor-rules.json and prune the rest")
+	fmt.Println("                                 (--frozen reinstalls exactly what the lockfile records)")
+	fmt.Println("  upgrade <ruleKey> | --all      Upgrade a rule (or every outdated rule) to the latest version")
+	fmt.Println("                                 (--on-local-changes=overwrite|keep|merge, --unpin)")
+	fmt.Println("  update <ruleKey> | --all       (Alias for 'upgrade') Update a rule to the latest version")
+	fmt.Println("  outdated [--json]              List rules with newer upstream versions (exits 1 if any)")
 	fmt.Println("  list [--detailed]              List installed rules, optionally with details")
 	fmt.Println("  set-lock-location [--root]     Set lockfile location (default is .cursor/rules)")
+	fmt.Println("  lock migrate                   Rewrite the lockfile in the current schema version")
+	fmt.Println("  verify [--json]                Check installed rule files against lockfile hashes")
+	fmt.Println("  status [--local]               Show local and upstream changes to installed rules")
+	fmt.Println("  adopt [file...]                Track untracked .mdc files in the lockfile")
+	fmt.Println("  diff <ruleKey>                 Show local edits to a rule as a unified diff")
+	fmt.Println("  reset <ruleKey>                Discard local edits and restore the installed version")
+	fmt.Println("  resolve <ruleKey>              Mark merge conflicts left by an upgrade as resolved")
 	fmt.Println("  share [--output=FILE] [--embed] Generate shareable rule definitions")
 	fmt.Println("  restore <file|url> [--auto-resolve=OPTION] Restore rules from shareable definitions")
 	fmt.Println("                                              (file|url can be a local file path or a URL)")
@@ -562,6 +1098,12 @@ func showHelp() {
 	fmt.Println("  --init                         Initialize Cursor Rules with just the init template")
 	fmt.Println("  --setup                        Run project type detection and setup appropriate rules")
 	fmt.Println("  --debug                        Enable debug output (for troubleshooting)")
+	fmt.Println("  --yes                          Answer yes to every confirmation prompt")
+	fmt.Println("  --no-input                     Never prompt (prompts also fail when stdin is not a terminal)")
+	fmt.Println("  --rate-limit-wait=DURATION     Wait up to this long for a GitHub rate limit to reset (default 0)")
+	fmt.Println("  --offline                      Use only the local cache, never the network")
+	fmt.Println("  --concurrency=N                Download up to N rules at once (default 4)")
+	fmt.Println("  --lock-timeout=DURATION        Wait this long for another cursor-rules process (default 30s)")
 	fmt.Println("\nExamples:")
 	fmt.Println("  cursor-rules add https://github.com/user/repo/blob/main/path/to/rule.mdc")
 	fmt.Println("  cursor-rules add ./local/path/to/rule.mdc")
@@ -569,6 +1111,7 @@ func showHelp() {
 	fmt.Println("  cursor-rules --debug add fireharp/monorepo")
 	fmt.Println("  cursor-rules upgrade my-rule")
 	fmt.Println("  cursor-rules list --detailed")
+	fmt.Println("  cursor-rules install")
 }
 
 // runInitCommand initializes cursor rules with just the init template.
@@ -579,7 +1122,7 @@ func runInitCommand(cursorDir string) {
 	initTemplate, ok := templates.Categories["general"].Templates["init"]
 	if !ok {
 		fmt.Println("Error: Init template not found")
-		os.Exit(1)
+		exitOnError(1)
 	}
 
 	// Modify the init template to include CR_SETUP as an alias
@@ -596,13 +1139,13 @@ func runInitCommand(cursorDir string) {
 	err := os.WriteFile(initPath, []byte(initTemplate.Content), 0o600)
 	if err != nil {
 		fmt.Printf("Error writing init template: %v\n", err)
-		os.Exit(1)
+		exitOnError(1)
 	}
 
 	// Add the init template using the reference-based approach
 	if err := manager.AddRuleByReference(cursorDir, initPath); err != nil {
 		fmt.Printf("Error creating init template: %v\n", err)
-		os.Exit(1)
+		exitOnError(1)
 	}
 
 	fmt.Println("Added init template. Run CursorRules.setup or CR_SETUP in Cursor to continue setup.")
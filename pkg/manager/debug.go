@@ -2,14 +2,42 @@ package manager
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 )
 
 // Debug controls whether debug messages are printed
 var Debug = false
 
+// Values such as tokens that must never appear in debug output
+var (
+	secretsMu sync.Mutex
+	secrets   []string
+)
+
+// registerSecret makes Debugf redact a value from everything it prints
+func registerSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets = append(secrets, secret)
+}
+
+// redactSecrets replaces every registered secret in s
+func redactSecrets(s string) string {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+	return s
+}
+
 // Debugf prints a debug message if debug output is enabled
 func Debugf(format string, args ...interface{}) {
 	if Debug {
-		fmt.Printf("Debug: "+format+"\n", args...)
+		fmt.Print(redactSecrets(fmt.Sprintf("Debug: "+format+"\n", args...)))
 	}
 }
@@ -3,6 +3,7 @@ package manager
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ErrReferenceType is returned when there's an issue with the reference type.
@@ -25,10 +26,27 @@ func (e *ErrGitHubAccess) Error() string {
 	return fmt.Sprintf("GitHub access error for '%s': %v", e.Reference, e.Cause)
 }
 
+// ErrInvalidDownload is returned when downloaded content can't be a rule or share file:
+// it is too large, binary, or an HTML page instead of the raw file.
+type ErrInvalidDownload struct {
+	URL    string
+	Reason string
+}
+
+func (e *ErrInvalidDownload) Error() string {
+	return fmt.Sprintf("invalid download from '%s': %s", e.URL, e.Reason)
+}
+
 // ErrGitHubRateLimit is returned when GitHub rate limit is exceeded.
 type ErrGitHubRateLimit struct {
 	Reference string
 	ResetTime string
+
+	// When the limit resets; ResetTime is the same time formatted for display
+	Reset time.Time
+
+	// Whether the request carried a token; anonymous requests have a much lower limit
+	Authenticated bool
 }
 
 func (e *ErrGitHubRateLimit) Error() string {
@@ -54,6 +72,72 @@ func (e *ErrRuleNotFound) Error() string {
 	return fmt.Sprintf("rule not found: %s", e.RuleKey)
 }
 
+// ErrContentMismatch is returned when downloaded content does not match the hash in the lockfile.
+type ErrContentMismatch struct {
+	RuleKey  string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrContentMismatch) Error() string {
+	return fmt.Sprintf("content of rule '%s' does not match the lockfile: expected sha256 %s, got %s",
+		e.RuleKey, e.Expected, e.Actual)
+}
+
+// ErrLockBusy is returned when another cursor-rules process holds the project lock.
+type ErrLockBusy struct {
+	Path   string
+	Waited time.Duration
+}
+
+func (e *ErrLockBusy) Error() string {
+	return fmt.Sprintf("another cursor-rules process is running in this project (waited %s for %s)", e.Waited, e.Path)
+}
+
+// ErrCacheMiss is returned when content is not in the local content cache.
+type ErrCacheMiss struct {
+	Hash string
+}
+
+func (e *ErrCacheMiss) Error() string {
+	return fmt.Sprintf("content with sha256 %s is not in the cache", e.Hash)
+}
+
+// ErrOfflineCacheMiss is returned in offline mode when something that would have to be
+// downloaded is not in the local cache.
+type ErrOfflineCacheMiss struct {
+	// The URL or reference that is missing
+	Item string
+}
+
+func (e *ErrOfflineCacheMiss) Error() string {
+	return fmt.Sprintf("offline mode: %s is not in the local cache", e.Item)
+}
+
+// ErrInputRequired is returned when a question needs an answer but stdin is not a
+// terminal or prompting was disabled with --no-input.
+type ErrInputRequired struct {
+	Question string
+	Hint     string
+}
+
+func (e *ErrInputRequired) Error() string {
+	return fmt.Sprintf("%q needs an answer but prompting is not possible; %s", e.Question, e.Hint)
+}
+
+// ErrRuleKeyConflict is returned when a reference would be installed under the key of a
+// rule that was installed from somewhere else.
+type ErrRuleKeyConflict struct {
+	RuleKey string
+
+	// The reference the existing rule was installed from
+	Existing string
+}
+
+func (e *ErrRuleKeyConflict) Error() string {
+	return fmt.Sprintf("rule key %s is already used by %s, remove that rule first", e.RuleKey, e.Existing)
+}
+
 // ErrTemplateFound is a special error indicating a template was found.
 // This replaces the string-based "template_found:" error pattern.
 type ErrTemplateFound struct {
@@ -71,6 +155,42 @@ func IsRuleNotFoundError(err error) bool {
 	return errors.As(err, &notFoundErr)
 }
 
+// IsContentMismatchError checks if an error is an ErrContentMismatch.
+func IsContentMismatchError(err error) bool {
+	var mismatchErr *ErrContentMismatch
+	return errors.As(err, &mismatchErr)
+}
+
+// IsLockBusyError checks if an error is an ErrLockBusy.
+func IsLockBusyError(err error) bool {
+	var busyErr *ErrLockBusy
+	return errors.As(err, &busyErr)
+}
+
+// IsCacheMissError checks if an error is an ErrCacheMiss.
+func IsCacheMissError(err error) bool {
+	var missErr *ErrCacheMiss
+	return errors.As(err, &missErr)
+}
+
+// IsOfflineCacheMissError checks if an error is an ErrOfflineCacheMiss.
+func IsOfflineCacheMissError(err error) bool {
+	var offlineErr *ErrOfflineCacheMiss
+	return errors.As(err, &offlineErr)
+}
+
+// IsInputRequiredError checks if an error is an ErrInputRequired.
+func IsInputRequiredError(err error) bool {
+	var inputErr *ErrInputRequired
+	return errors.As(err, &inputErr)
+}
+
+// IsRuleKeyConflictError checks if an error is an ErrRuleKeyConflict.
+func IsRuleKeyConflictError(err error) bool {
+	var conflictErr *ErrRuleKeyConflict
+	return errors.As(err, &conflictErr)
+}
+
 // IsTemplateFoundError checks if an error is an ErrTemplateFound.
 func IsTemplateFoundError(err error) bool {
 	var templateFoundErr *ErrTemplateFound
@@ -89,6 +209,12 @@ func IsGitHubAccessError(err error) bool {
 	return errors.As(err, &accessErr)
 }
 
+// IsInvalidDownloadError checks if an error is an ErrInvalidDownload.
+func IsInvalidDownloadError(err error) bool {
+	var downloadErr *ErrInvalidDownload
+	return errors.As(err, &downloadErr)
+}
+
 // IsLocalFileAccessError checks if an error is an ErrLocalFileAccess.
 func IsLocalFileAccessError(err error) bool {
 	var accessErr *ErrLocalFileAccess
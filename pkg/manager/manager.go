@@ -3,11 +3,34 @@
 //
 // - manager.go: Main package declaration and imports
 // - manager_lockfile.go: Lockfile operations and functionality
+// - manager_lockfile_migrate.go: Lockfile schema versions and migrations
 // - manager_rules.go: Rule management (add, remove, list)
 // - manager_upgrade.go: Rule upgrade functionality
 // - manager_share.go: Sharing and restoring rules
 // - manager_utils.go: Utility functions and shared types
 // - manager_github.go: GitHub-specific operations
+// - manager_githubdir.go: GitHub directory (tree URL) rules with many files
+// - manager_install.go: Manifest loading and the declarative install
+// - manager_verify.go: Verification of installed files against lockfile hashes
+// - manager_status.go: git status-like comparison of rules with the lockfile and upstream
+// - manager_adopt.go: Adoption of untracked rule files into the lockfile
+// - manager_cache.go: Content-addressable cache of installed rule content
+// - manager_diff.go: Line diffs of local edits, and resetting them
+// - manager_merge.go: Three-way merging of local edits during upgrades
+// - manager_semver.go: Semantic version parsing for tag upgrades
+// - manager_outdated.go: Detection of rules whose upstream has moved
+// - manager_prompt.go: Interactive questions and the flags that answer them
+// - manager_auth.go: GitHub credentials and authenticated requests
+// - manager_ratelimit.go: Detection of GitHub rate limits and waiting for their reset
+// - manager_user_config.go: Per-user settings in ~/.cursor-rules/config.json
+// - manager_httpclient.go: Shared HTTP client with timeouts, retries, proxy and CA settings
+// - manager_download.go: Size, content type and HTML checks of downloaded files
+// - manager_httpcache.go: Conditional GitHub requests answered from a response cache
+// - manager_offline.go: Offline mode that only uses the local cache
+// - manager_hosts.go: GitHub and GitHub Enterprise hosts
+// - manager_parallel.go: Bounded parallel downloads with progress output
+// - manager_config.go: Per-project settings
+// - manager_flock.go: Advisory locking of a project for the duration of a command
 package manager
 
 // This file serves as a hub for the cursor-rules manager package.
@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fireharp/cursor-rules/pkg/templates"
+)
+
+// findLockedContent returns the index of the lockfile rule that installed content with the
+// given hash, either as a single file or as one file of a GitHub directory.
+func findLockedContent(lock *LockFile, contentHash string) (int, bool) {
+	for i, rule := range lock.Rules {
+		if rule.ContentSHA256 == contentHash {
+			return i, true
+		}
+		for _, hash := range rule.FileSHA256 {
+			if hash == contentHash {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// cachedDownloadOrigins maps the hashes of .mdc files in the response cache that were
+// downloaded at a commit to a GitHub file rule for that commit. Files downloaded by
+// branch or tag name are left out, the name doesn't say which kind of ref it was.
+func cachedDownloadOrigins() map[string]RuleSource {
+	origins := make(map[string]RuleSource)
+	for _, entry := range loadHTTPCacheEntries() {
+		for _, host := range allGitHubHosts() {
+			rest, ok := strings.CutPrefix(entry.URL, host.RawURL+"/")
+			if !ok {
+				continue
+			}
+			parts := strings.SplitN(rest, "/", 4)
+			if len(parts) != 4 || !isGitCommitHash(parts[2]) || !strings.HasSuffix(parts[3], ".mdc") {
+				break
+			}
+			blobURL := fmt.Sprintf("%s/%s/%s/blob/%s/%s", host.WebURL, parts[0], parts[1], parts[2], parts[3])
+			origins[calculateSHA256(entry.Body)] = RuleSource{
+				SourceType: SourceTypeGitHubFile,
+				Reference:  blobURL,
+				GitRef:     "commit=" + parts[2],
+				SourceURL:  blobURL,
+			}
+			break
+		}
+	}
+	return origins
+}
+
+// identifyRuleOrigin looks for a known source with exactly the given content: a download
+// in the response cache or a built-in template. The returned rule carries the source
+// fields only; the caller fills in the key and files.
+func identifyRuleOrigin(contentHash string, downloads map[string]RuleSource) (RuleSource, bool) {
+	if origin, ok := downloads[contentHash]; ok {
+		return origin, true
+	}
+
+	// The reference is the template's key, the file may have been renamed since
+	categories := make([]string, 0, len(templates.Categories))
+	for category := range templates.Categories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		keys := make([]string, 0, len(templates.Categories[category].Templates))
+		for key := range templates.Categories[category].Templates {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			tmpl := templates.Categories[category].Templates[key]
+			if calculateSHA256([]byte(tmpl.Content)) == contentHash {
+				return RuleSource{
+					SourceType: SourceTypeBuiltIn,
+					Reference:  key,
+					Category:   category,
+				}, true
+			}
+		}
+	}
+
+	return RuleSource{}, false
+}
+
+// hasRuleSource checks if one of the rules is already installed from the source of origin.
+func hasRuleSource(rules []RuleSource, origin RuleSource) bool {
+	for _, rule := range rules {
+		if rule.SourceType == origin.SourceType && rule.Reference == origin.Reference {
+			return true
+		}
+		if origin.SourceURL != "" && rule.SourceURL == origin.SourceURL {
+			return true
+		}
+	}
+	return false
+}
+
+// AdoptRules registers untracked .mdc files in the lockfile. If paths is empty every
+// untracked file is adopted, otherwise only the given paths (relative to .cursor/rules).
+// A file with the content of a tracked rule whose file is missing is taken to be that
+// file moved, and the rule is updated instead. Files whose content matches a known source
+// that isn't installed yet are recorded with that source, all others as SourceTypeUnmanaged.
+func AdoptRules(cursorDir string, paths []string) ([]RuleSource, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	report, err := VerifyRules(cursorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked := make(map[string]bool)
+	var candidates []string
+	for _, f := range report.Files {
+		if f.Status == FileStatusUntracked {
+			untracked[f.Path] = true
+			candidates = append(candidates, f.Path)
+		}
+	}
+
+	if len(paths) > 0 {
+		candidates = candidates[:0]
+		for _, p := range paths {
+			p = filepath.ToSlash(filepath.Clean(p))
+			if !untracked[p] {
+				return nil, fmt.Errorf("%s is not an untracked rule file", p)
+			}
+			candidates = append(candidates, p)
+		}
+		sort.Strings(candidates)
+	}
+
+	existingKeys := buildExistingRuleSet(lock)
+	downloads := cachedDownloadOrigins()
+	var adopted, relinked []RuleSource
+
+	for _, file := range candidates {
+		content, err := os.ReadFile(ruleFilePath(cursorDir, file))
+		if err != nil {
+			return nil, &ErrLocalFileAccess{Path: file, Cause: err}
+		}
+
+		// The adopted content becomes the pristine version for 'diff' and 'reset'
+		hash, err := cacheContent(content)
+		if err != nil {
+			Debugf("AdoptRules: could not cache content of %s: %v\n", file, err)
+			hash = calculateSHA256(content)
+		}
+
+		rule := RuleSource{
+			SourceType: SourceTypeUnmanaged,
+			Reference:  file,
+		}
+		if i, ok := findLockedContent(lock, hash); ok {
+			locked := &lock.Rules[i]
+			if locked.SourceType != SourceTypeGitHubDir && len(locked.LocalFiles) == 1 &&
+				!fileExists(ruleFilePath(cursorDir, locked.LocalFiles[0])) {
+				// The rule's file was moved or renamed, it is tracked at its new path
+				locked.LocalFiles = []string{file}
+				relinked = append(relinked, *locked)
+				continue
+			}
+			// Otherwise it is a copy of a tracked rule. Recording the same source twice
+			// would leave two entries upgrading one reference, so the copy is unmanaged
+		} else if origin, ok := identifyRuleOrigin(hash, downloads); ok &&
+			!hasRuleSource(lock.Rules, origin) && !hasRuleSource(adopted, origin) {
+			rule = origin
+		}
+
+		rule.Key = findAvailableKey(strings.TrimSuffix(file, ".mdc"), existingKeys)
+		rule.LocalFiles = []string{file}
+		rule.ContentSHA256 = hash
+		existingKeys[rule.Key] = true
+
+		adopted = append(adopted, rule)
+	}
+
+	if len(adopted) == 0 && len(relinked) == 0 {
+		return nil, nil
+	}
+
+	lock.Rules = append(lock.Rules, adopted...)
+	if err := lock.Save(cursorDir); err != nil {
+		return nil, fmt.Errorf("failed to update lockfile: %w", err)
+	}
+
+	return append(relinked, adopted...), nil
+}
+
+// SyncLocalRules adopts every untracked rule file in the cursor rules directory.
+//
+// Deprecated: use AdoptRules, which also reports what was adopted.
+func SyncLocalRules(cursorDir string) error {
+	_, err := AdoptRules(cursorDir, nil)
+	return err
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAdoptRules tests that untracked files are adopted as unmanaged rules unless their source
+// is known and not installed yet.
+func TestAdoptRules(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	files := map[string]string{
+		"known.mdc": "shared content",
+		"copy.mdc":  "shared content",
+		"stray.mdc": "stray content",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cursorDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:           "known",
+		SourceType:    SourceTypeGitHubShorthand,
+		Reference:     "user/known",
+		LocalFiles:    []string{"known.mdc"},
+		ContentSHA256: calculateSHA256([]byte("shared content")),
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	// Unknown paths are rejected
+	if _, err := AdoptRules(cursorDir, []string{"known.mdc"}); err == nil {
+		t.Error("Expected error when adopting a tracked file, got nil")
+	}
+
+	adopted, err := AdoptRules(cursorDir, nil)
+	if err != nil {
+		t.Fatalf("AdoptRules returned error: %v", err)
+	}
+	if len(adopted) != 2 {
+		t.Fatalf("Expected 2 adopted rules, got %+v", adopted)
+	}
+
+	byKey := map[string]RuleSource{}
+	for _, rule := range adopted {
+		byKey[rule.Key] = rule
+	}
+	// A copy of a tracked rule doesn't get a second entry for the same reference
+	if copyRule := byKey["copy"]; copyRule.SourceType != SourceTypeUnmanaged {
+		t.Errorf("Expected copy.mdc of a tracked rule to be unmanaged, got %+v", copyRule)
+	}
+	if stray := byKey["stray"]; stray.SourceType != SourceTypeUnmanaged {
+		t.Errorf("Expected stray.mdc to be unmanaged, got %+v", stray)
+	}
+
+	// Everything is tracked now
+	report, err := VerifyRules(cursorDir)
+	if err != nil {
+		t.Fatalf("VerifyRules returned error: %v", err)
+	}
+	if report.HasDrift() {
+		t.Errorf("Expected no drift after adopting, got %+v", report.Files)
+	}
+}
+
+// TestAdoptRules_Origins tests that a moved rule file is tracked at its new path and that
+// downloads in the response cache identify where a file came from.
+func TestAdoptRules_Origins(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	t.Setenv(CacheDirEnv, t.TempDir())
+
+	commit := "6666666666666666666666666666666666666666"
+	rawURL := githubDotCom.RawURL + "/owner/repo/" + commit + "/rules/cached.mdc"
+	if err := saveHTTPCacheEntry(&httpCacheEntry{URL: rawURL, Body: []byte("cached content")}); err != nil {
+		t.Fatalf("Failed to cache download: %v", err)
+	}
+
+	files := map[string]string{
+		"moved/known.mdc": "known content",
+		"cached.mdc":      "cached content",
+	}
+	for name, content := range files {
+		path := filepath.Join(cursorDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:           "known",
+		SourceType:    SourceTypeGitHubShorthand,
+		Reference:     "user/known",
+		LocalFiles:    []string{"known.mdc"},
+		ContentSHA256: calculateSHA256([]byte("known content")),
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	if _, err := AdoptRules(cursorDir, nil); err != nil {
+		t.Fatalf("AdoptRules returned error: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 2 {
+		t.Fatalf("Expected the moved rule to be updated and one rule adopted, got %+v", lock.Rules)
+	}
+	if known := lock.Rules[0]; known.Key != "known" || known.LocalFiles[0] != "moved/known.mdc" {
+		t.Errorf("Expected known to be tracked at moved/known.mdc, got %+v", known)
+	}
+	expectedURL := "https://github.com/owner/repo/blob/" + commit + "/rules/cached.mdc"
+	if cached := lock.Rules[1]; cached.SourceType != SourceTypeGitHubFile || cached.SourceURL != expectedURL ||
+		cached.GitRef != "commit="+commit {
+		t.Errorf("Expected cached.mdc to match %s, got %+v", expectedURL, cached)
+	}
+}
+
+// TestAdoptRules_RenamedTemplate tests that a renamed copy of a built-in template is recorded
+// with the template's key, so it can be reinstalled and upgraded.
+func TestAdoptRules_RenamedTemplate(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	setupTestTemplates()
+
+	path := filepath.Join(cursorDir, "test-rule-2.mdc")
+	if err := os.WriteFile(path, []byte("This is a test rule content"), 0o644); err != nil {
+		t.Fatalf("Failed to write rule: %v", err)
+	}
+
+	adopted, err := AdoptRules(cursorDir, nil)
+	if err != nil {
+		t.Fatalf("AdoptRules returned error: %v", err)
+	}
+	if len(adopted) != 1 {
+		t.Fatalf("Expected one adopted rule, got %+v", adopted)
+	}
+	rule := adopted[0]
+	if rule.SourceType != SourceTypeBuiltIn || rule.Key != "test-rule-2" || rule.Reference != "test-rule" ||
+		rule.Category != "general" {
+		t.Fatalf("Expected built-in test-rule-2 from general/test-rule, got %+v", rule)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if _, err := InstallFrozen(context.Background(), cursorDir); err != nil {
+		t.Fatalf("InstallFrozen returned error: %v", err)
+	}
+	if !fileExists(path) {
+		t.Error("Expected the frozen install to restore test-rule-2.mdc")
+	}
+
+	if err := UpgradeRule(cursorDir, rule.Key); err != nil {
+		t.Errorf("UpgradeRule returned error: %v", err)
+	}
+}
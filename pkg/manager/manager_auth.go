@@ -0,0 +1,188 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Environment variables that hold a GitHub token, in order of precedence.
+const (
+	GitHubTokenEnv   = "GITHUB_TOKEN"
+	GitHubTokenEnvGH = "GH_TOKEN"
+)
+
+// Hosts a token found in ~/.netrc or the gh CLI config is accepted for on github.com. Other
+// hosts use the host names of their web and API URLs, see credentialHosts.
+var githubCredentialHosts = []string{"api.github.com", "github.com"}
+
+// The token is looked up on first use; tests replace the Once to look it up again
+var (
+	githubTokenOnce  = &sync.Once{}
+	githubTokenValue string
+)
+
+// lookupNetrcPassword returns the password of the first machine entry in a .netrc file
+// that matches one of hosts.
+func lookupNetrcPassword(path string, hosts []string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	// Entries are whitespace separated "machine <host> login <user> password <secret>" tokens
+	fields := strings.Fields(string(data))
+	machine := ""
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				i++
+				machine = fields[i]
+			}
+		case "default":
+			machine = ""
+		case "password":
+			if i+1 >= len(fields) {
+				return ""
+			}
+			i++
+			for _, host := range hosts {
+				if machine == host {
+					return fields[i]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// lookupGHConfigToken returns the oauth_token stored for one of hosts in the gh CLI config
+// (hosts.yml). Tokens gh keeps in the system keyring are not visible here.
+func lookupGHConfigToken(path string, hosts []string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	// hosts.yml maps each host to its settings, e.g. "github.com:\n    oauth_token: gho_..."
+	inHost := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			host := strings.TrimSuffix(strings.TrimSpace(line), ":")
+			inHost = false
+			for _, h := range hosts {
+				if host == h {
+					inHost = true
+				}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if inHost && ok && key == "oauth_token" {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// lookupGitHubToken finds a GitHub token and reports where it came from: GITHUB_TOKEN or
+// GH_TOKEN, the githubToken field of the user config, the gh CLI config, then ~/.netrc.
+func lookupGitHubToken() (token, source string) {
+	for _, env := range []string{GitHubTokenEnv, GitHubTokenEnvGH} {
+		if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+			return token, env
+		}
+	}
+
+	if config, err := loadUserConfig(); err != nil {
+		Debugf("Failed to load config file: %v\n", err)
+	} else if config.GitHubToken != "" {
+		return config.GitHubToken, "config.json"
+	}
+
+	return lookupCredentialFiles(githubCredentialHosts)
+}
+
+// lookupCredentialFiles returns the token the gh CLI config or, failing that, ~/.netrc
+// stores for one of hosts, and which of the two it came from.
+func lookupCredentialFiles(hosts []string) (token, source string) {
+	ghConfigDir := os.Getenv("GH_CONFIG_DIR")
+	if ghConfigDir == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			ghConfigDir = filepath.Join(configDir, "gh")
+		}
+	}
+	if ghConfigDir != "" {
+		if token := lookupGHConfigToken(filepath.Join(ghConfigDir, "hosts.yml"), hosts); token != "" {
+			return token, "gh config"
+		}
+	}
+
+	netrcPath := os.Getenv("NETRC")
+	if netrcPath == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			netrcPath = filepath.Join(homeDir, ".netrc")
+		}
+	}
+	if netrcPath != "" {
+		if token := lookupNetrcPassword(netrcPath, hosts); token != "" {
+			return token, "netrc"
+		}
+	}
+
+	return "", ""
+}
+
+// getGitHubToken returns the GitHub token to authenticate with, or "" for anonymous access.
+// It is looked up once per process and redacted from debug output from then on.
+func getGitHubToken() string {
+	githubTokenOnce.Do(func() {
+		token, source := lookupGitHubToken()
+		if token == "" {
+			Debugf("No GitHub token found, using anonymous access\n")
+			return
+		}
+		registerSecret(token)
+		githubTokenValue = token
+		Debugf("Using GitHub token from %s\n", source)
+	})
+	return githubTokenValue
+}
+
+// tokenForHost returns the token to send to a GitHub host, or "" for anonymous access.
+func tokenForHost(host *GitHubHost) string {
+	if host == githubDotCom {
+		return getGitHubToken()
+	}
+	return host.Token
+}
+
+// newGitHubRequest creates a GET request to the API or raw content host of a GitHub host,
+// authenticated with the user's token for that host if there is one. Tokens are never
+// sent anywhere else, nor over plain http unless the host allows it.
+func newGitHubRequest(ctx context.Context, requestURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if host := findGitHubHostForRequest(req.URL); host != nil {
+		if token := tokenForHost(host); token != "" && (req.URL.Scheme == "https" || host.AllowInsecure) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return req, nil
+}
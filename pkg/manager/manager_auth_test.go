@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestLookupGitHubToken tests the order in which token sources are consulted.
+func TestLookupGitHubToken(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	configPath := filepath.Join(tempDir, "config.json")
+	ghConfigDir := filepath.Join(tempDir, "gh")
+	netrcPath := filepath.Join(tempDir, ".netrc")
+
+	t.Setenv(GitHubTokenEnv, "")
+	t.Setenv(GitHubTokenEnvGH, "")
+	t.Setenv(UserConfigPathEnv, configPath)
+	t.Setenv("GH_CONFIG_DIR", ghConfigDir)
+	t.Setenv("NETRC", netrcPath)
+
+	if token, _ := lookupGitHubToken(); token != "" {
+		t.Errorf("Expected no token without any source, got %q", token)
+	}
+
+	netrc := "machine example.com login me password other\n" +
+		"machine api.github.com\n  login me\n  password netrc-token\n"
+	if err := os.WriteFile(netrcPath, []byte(netrc), 0o600); err != nil {
+		t.Fatalf("Failed to write netrc: %v", err)
+	}
+	if token, source := lookupGitHubToken(); token != "netrc-token" || source != "netrc" {
+		t.Errorf("Expected netrc-token from netrc, got %q from %q", token, source)
+	}
+
+	if err := os.MkdirAll(ghConfigDir, 0o755); err != nil {
+		t.Fatalf("Failed to create gh config dir: %v", err)
+	}
+	hosts := "enterprise.example.com:\n    oauth_token: other\ngithub.com:\n    user: me\n    oauth_token: gh-token\n"
+	if err := os.WriteFile(filepath.Join(ghConfigDir, "hosts.yml"), []byte(hosts), 0o600); err != nil {
+		t.Fatalf("Failed to write hosts.yml: %v", err)
+	}
+	if token, source := lookupGitHubToken(); token != "gh-token" || source != "gh config" {
+		t.Errorf("Expected gh-token from gh config, got %q from %q", token, source)
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"githubToken": "config-token"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if token, source := lookupGitHubToken(); token != "config-token" || source != "config.json" {
+		t.Errorf("Expected config-token from config.json, got %q from %q", token, source)
+	}
+
+	t.Setenv(GitHubTokenEnv, "env-token")
+	if token, source := lookupGitHubToken(); token != "env-token" || source != GitHubTokenEnv {
+		t.Errorf("Expected env-token from %s, got %q from %q", GitHubTokenEnv, token, source)
+	}
+}
+
+// TestNewGitHubRequest tests that the token is only sent to GitHub hosts and kept out of debug output.
+func TestNewGitHubRequest(t *testing.T) {
+	const token = "ghp_secret"
+	t.Setenv(GitHubTokenEnv, token)
+
+	oldOnce, oldValue := githubTokenOnce, githubTokenValue
+	githubTokenOnce, githubTokenValue = &sync.Once{}, ""
+	defer func() {
+		githubTokenOnce, githubTokenValue = oldOnce, oldValue
+	}()
+
+	tests := []struct {
+		url        string
+		expectAuth bool
+	}{
+		{githubDotCom.APIURL + "/repos/owner/repo/branches/main", true},
+		{githubDotCom.RawURL + "/owner/repo/main/rule.mdc", true},
+		{"https://example.com/shared-rules.json", false},
+	}
+
+	for _, tt := range tests {
+		req, err := newGitHubRequest(context.Background(), tt.url)
+		if err != nil {
+			t.Fatalf("newGitHubRequest returned error: %v", err)
+		}
+		auth := req.Header.Get("Authorization")
+		if tt.expectAuth && auth != "Bearer "+token {
+			t.Errorf("%s: expected bearer token, got %q", tt.url, auth)
+		}
+		if !tt.expectAuth && auth != "" {
+			t.Errorf("%s: expected no Authorization header, got %q", tt.url, auth)
+		}
+	}
+
+	if got := redactSecrets("token=" + token); got != "token=[REDACTED]" {
+		t.Errorf("Expected the token to be redacted, got %q", got)
+	}
+}
+
+// TestLoadGitHubHosts_CredentialFiles tests that a configured host without a token uses the
+// gh CLI config or ~/.netrc entry for its own host name.
+func TestLoadGitHubHosts_CredentialFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	configPath := filepath.Join(tempDir, "config.json")
+	netrcPath := filepath.Join(tempDir, ".netrc")
+	t.Setenv(UserConfigPathEnv, configPath)
+	t.Setenv("GH_CONFIG_DIR", filepath.Join(tempDir, "gh"))
+	t.Setenv("NETRC", netrcPath)
+	setGitHubHosts(t)
+
+	config := `{"githubHosts": [{"webURL": "https://ghe.example.com"}, {"webURL": "https://other.example.com", "token": "explicit"}]}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	netrc := "machine github.com login me password github-token\n" +
+		"machine ghe.example.com login me password ghe-token\n" +
+		"machine other.example.com login me password other-token\n"
+	if err := os.WriteFile(netrcPath, []byte(netrc), 0o600); err != nil {
+		t.Fatalf("Failed to write netrc: %v", err)
+	}
+
+	if err := LoadGitHubHosts(); err != nil {
+		t.Fatalf("LoadGitHubHosts returned error: %v", err)
+	}
+	if GitHubHosts[0].Token != "ghe-token" {
+		t.Errorf("Expected the netrc token for ghe.example.com, got %q", GitHubHosts[0].Token)
+	}
+	if GitHubHosts[1].Token != "explicit" {
+		t.Errorf("Expected the configured token to win, got %q", GitHubHosts[1].Token)
+	}
+}
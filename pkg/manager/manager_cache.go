@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CacheDirEnv overrides the location of the content cache (default ~/.cursor-rules/cache).
+const CacheDirEnv = "CURSOR_RULES_CACHE_DIR"
+
+// getCacheDir returns the root of the content-addressable cache.
+func getCacheDir() (string, error) {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home dir: %w", err)
+	}
+	return filepath.Join(homeDir, ".cursor-rules", "cache"), nil
+}
+
+// getCachedContentPath returns where content with the given hash is stored.
+// Entries are sharded by the first two hex digits to keep directories small.
+func getCachedContentPath(hash string) (string, error) {
+	if len(hash) < 2 {
+		return "", fmt.Errorf("invalid content hash: %q", hash)
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "sha256", hash[:2], hash), nil
+}
+
+// cacheContent stores content in the cache under its SHA256 hash and returns the hash.
+func cacheContent(content []byte) (string, error) {
+	hash := calculateSHA256(content)
+	path, err := getCachedContentPath(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// Content-addressed entries never change once written
+	if fileExists(path) {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return hash, nil
+}
+
+// loadCachedContent returns the cached content for a hash, verifying it on the way out.
+func loadCachedContent(hash string) ([]byte, error) {
+	path, err := getCachedContentPath(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &ErrCacheMiss{Hash: hash}
+		}
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	if calculateSHA256(data) != hash {
+		// A corrupted entry is as good as a missing one
+		_ = os.Remove(path)
+		return nil, &ErrCacheMiss{Hash: hash}
+	}
+	return data, nil
+}
+
+// writeRuleFile writes an installed rule file and records its content in the cache,
+// so the pristine version is available to 'diff', 'reset' and merges later on.
+// Failing to cache is not fatal, the rule itself was installed.
+func writeRuleFile(path string, content []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return err
+	}
+
+	if _, err := cacheContent(content); err != nil {
+		Debugf("writeRuleFile: could not cache content of %s: %v\n", path, err)
+	}
+	return nil
+}
+
+// loadPristineContent returns the content a rule had when it was installed. It comes
+// from the cache if possible; otherwise the rule is fetched again from its recorded
+// source, checked against the lockfile hash and cached.
+func loadPristineContent(ctx context.Context, cursorDir string, rule RuleSource) ([]byte, error) {
+	if rule.ContentSHA256 == "" {
+		return nil, fmt.Errorf("rule %s has no content hash in the lockfile", rule.Key)
+	}
+
+	content, err := loadCachedContent(rule.ContentSHA256)
+	if err == nil || !IsCacheMissError(err) {
+		return content, err
+	}
+
+	Debugf("loadPristineContent: cache miss for %s, fetching from source\n", rule.Key)
+	content, fetchErr := fetchLockedContent(ctx, cursorDir, rule)
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w (and fetching it again failed: %v)", err, fetchErr)
+	}
+	if actual := calculateSHA256(content); actual != rule.ContentSHA256 {
+		return nil, &ErrContentMismatch{RuleKey: rule.Key, Expected: rule.ContentSHA256, Actual: actual}
+	}
+
+	if _, err := cacheContent(content); err != nil {
+		Debugf("loadPristineContent: failed to cache %s: %v\n", rule.Key, err)
+	}
+	return content, nil
+}
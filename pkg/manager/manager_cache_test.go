@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"os"
+	"testing"
+)
+
+// TestCacheContent tests storing and loading content by hash.
+func TestCacheContent(t *testing.T) {
+	content := []byte("# Cached rule\n")
+
+	hash, err := cacheContent(content)
+	if err != nil {
+		t.Fatalf("cacheContent returned error: %v", err)
+	}
+	if hash != calculateSHA256(content) {
+		t.Errorf("Expected hash %s, got %s", calculateSHA256(content), hash)
+	}
+
+	loaded, err := loadCachedContent(hash)
+	if err != nil {
+		t.Fatalf("loadCachedContent returned error: %v", err)
+	}
+	if string(loaded) != string(content) {
+		t.Errorf("Expected %q, got %q", content, loaded)
+	}
+
+	// Corrupted entries are treated as missing
+	path, err := getCachedContentPath(hash)
+	if err != nil {
+		t.Fatalf("getCachedContentPath returned error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("tampered"), 0o644); err != nil {
+		t.Fatalf("Failed to tamper with cache entry: %v", err)
+	}
+	if _, err := loadCachedContent(hash); !IsCacheMissError(err) {
+		t.Errorf("Expected ErrCacheMiss for corrupted entry, got %v", err)
+	}
+
+	if _, err := loadCachedContent(calculateSHA256([]byte("never cached"))); !IsCacheMissError(err) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
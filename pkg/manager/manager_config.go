@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectConfigFileName is the per-project settings file, stored in the .cursor directory.
+const ProjectConfigFileName = "cursor-rules.config.json"
+
+// Lockfile locations that can be stored in the project config.
+const (
+	LockLocationRules = "rules" // .cursor/rules/cursor-rules.lock (default)
+	LockLocationRoot  = "root"  // <project root>/cursor-rules.lock
+)
+
+// ProjectConfig holds settings that apply to a single project and must survive
+// between invocations.
+type ProjectConfig struct {
+	// Where the lockfile lives: "rules" (default) or "root"
+	LockFileLocation string `json:"lockFileLocation,omitempty"`
+}
+
+// getProjectConfigPath returns the path to the project config file.
+func getProjectConfigPath(cursorDir string) string {
+	// cursorDir is typically /path/to/project/.cursor/rules
+	return filepath.Join(filepath.Dir(cursorDir), ProjectConfigFileName)
+}
+
+// LoadProjectConfig loads the project config, returning defaults if it doesn't exist.
+func LoadProjectConfig(cursorDir string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(getProjectConfigPath(cursorDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ProjectConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read project config: %w", err)
+	}
+
+	var config ProjectConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse project config: %w", err)
+	}
+
+	switch config.LockFileLocation {
+	case "", LockLocationRules, LockLocationRoot:
+	default:
+		return nil, fmt.Errorf("invalid lockFileLocation in %s: %q (expected %q or %q)",
+			ProjectConfigFileName, config.LockFileLocation, LockLocationRules, LockLocationRoot)
+	}
+
+	return &config, nil
+}
+
+// Save writes the project config to disk.
+func (config *ProjectConfig) Save(cursorDir string) error {
+	configPath := getProjectConfigPath(cursorDir)
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize project config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for project config: %w", err)
+	}
+
+	if err := os.WriteFile(configPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write project config: %w", err)
+	}
+	return nil
+}
+
+// UseRootLockFile reports whether the project is configured to keep the lockfile in the project root.
+func (config *ProjectConfig) UseRootLockFile() bool {
+	return config.LockFileLocation == LockLocationRoot
+}
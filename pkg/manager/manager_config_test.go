@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSetLockFileLocation tests that the lockfile location is persisted and auto-detected.
+func TestSetLockFileLocation(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	lock := &LockFile{Rules: []RuleSource{{Key: "test-rule", SourceType: SourceTypeBuiltIn}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	// Move the lockfile to the project root
+	newPath, err := SetLockFileLocation(cursorDir, true)
+	if err != nil {
+		t.Fatalf("SetLockFileLocation returned error: %v", err)
+	}
+	rootPath := filepath.Join(tempDir, LockFileName)
+	if newPath != rootPath {
+		t.Errorf("Expected lockfile at %s, got %s", rootPath, newPath)
+	}
+	if fileExists(filepath.Join(cursorDir, LockFileName)) {
+		t.Errorf("Old lockfile was not removed")
+	}
+
+	// The setting must survive without any in-memory state
+	config, err := LoadProjectConfig(cursorDir)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig returned error: %v", err)
+	}
+	if !config.UseRootLockFile() {
+		t.Errorf("Expected root lockfile location to be persisted, got %q", config.LockFileLocation)
+	}
+
+	loaded, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("LoadLockFile returned error: %v", err)
+	}
+	if !loaded.IsInstalled("test-rule") {
+		t.Errorf("Lockfile content was not moved to the project root")
+	}
+
+	// A lockfile in both locations is an error
+	if err := os.WriteFile(filepath.Join(cursorDir, LockFileName), []byte(`{"rules":[]}`), 0o644); err != nil {
+		t.Fatalf("Failed to write second lockfile: %v", err)
+	}
+	if _, err := LoadLockFile(cursorDir); err == nil {
+		t.Error("Expected error when lockfiles exist in both locations, got nil")
+	}
+}
+
+// TestLoadProjectConfig_Invalid tests that an unknown lockfile location is rejected.
+func TestLoadProjectConfig_Invalid(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	configPath := getProjectConfigPath(cursorDir)
+	if err := os.WriteFile(configPath, []byte(`{"lockFileLocation": "elsewhere"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	if _, err := LoadProjectConfig(cursorDir); err == nil {
+		t.Error("Expected error for invalid lockFileLocation, got nil")
+	}
+}
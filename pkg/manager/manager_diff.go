@@ -0,0 +1,234 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change.
+const diffContextLines = 3
+
+// diffOpKind is the kind of a single line in a line diff.
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp is one line of a line diff. aIndex and bIndex are the positions in the
+// old and new text at which the line applies.
+type diffOp struct {
+	kind   diffOpKind
+	aIndex int
+	bIndex int
+	line   string
+}
+
+// splitLines splits text into lines, keeping the "\n" terminators so that a
+// missing final newline shows up as a difference.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a minimal line diff of a and b using the longest common subsequence.
+// Rule files are small, so the quadratic table is fine.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: diffEqual, aIndex: i, bIndex: j, line: a[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			// Deletions come before insertions, like diff(1)
+			ops = append(ops, diffOp{kind: diffDelete, aIndex: i, bIndex: j, line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: diffInsert, aIndex: i, bIndex: j, line: b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// hunkRange formats one side of a unified diff hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// unifiedDiff returns a unified diff from oldText to newText, or "" if they are equal.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	i := 0
+	for i < len(ops) {
+		// Find the next change
+		for i < len(ops) && ops[i].kind == diffEqual {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// Extend the hunk until the unchanged run between changes is too long to bridge
+		start := max(i-diffContextLines, 0)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != diffEqual {
+				end++
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].kind == diffEqual {
+				run++
+			}
+			if end+run == len(ops) || run > 2*diffContextLines {
+				end += min(run, diffContextLines)
+				break
+			}
+			end += run
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != diffInsert {
+				oldCount++
+			}
+			if op.kind != diffDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(ops[start].aIndex, oldCount), hunkRange(ops[start].bIndex, newCount))
+
+		for _, op := range ops[start:end] {
+			prefix := " "
+			switch op.kind {
+			case diffDelete:
+				prefix = "-"
+			case diffInsert:
+				prefix = "+"
+			}
+			sb.WriteString(prefix + op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// DiffRule returns a unified diff of the local edits to a rule, compared with the
+// content it was installed with. An empty string means there are no local changes.
+func DiffRule(ctx context.Context, cursorDir, ruleKey string) (string, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	rule, err := findRule(lock, ruleKey)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, fileRule := range ruleFileSources(*rule) {
+		pristine, err := loadPristineContent(ctx, cursorDir, fileRule)
+		if err != nil {
+			return "", err
+		}
+
+		for _, file := range fileRule.LocalFiles {
+			local, err := os.ReadFile(ruleFilePath(cursorDir, file))
+			if err != nil && !os.IsNotExist(err) {
+				return "", &ErrLocalFileAccess{Path: file, Cause: err}
+			}
+
+			// A deleted file shows up as every line removed
+			newName := "b/" + file
+			if os.IsNotExist(err) {
+				newName = "/dev/null"
+			}
+			sb.WriteString(unifiedDiff("a/"+file, newName, string(pristine), string(local)))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// ResetRule discards local edits to a rule by restoring the content it was installed with.
+func ResetRule(ctx context.Context, cursorDir, ruleKey string) error {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	rule, err := findRule(lock, ruleKey)
+	if err != nil {
+		return err
+	}
+
+	for _, fileRule := range ruleFileSources(*rule) {
+		pristine, err := loadPristineContent(ctx, cursorDir, fileRule)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range fileRule.LocalFiles {
+			if err := writeRuleFile(ruleFilePath(cursorDir, file), pristine, 0o644); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", file, err)
+			}
+		}
+	}
+
+	// Restoring the pristine content also discards any conflicted merge
+	if rule.MergeConflict {
+		rule.MergeConflict = false
+		if err := lock.Save(cursorDir); err != nil {
+			return fmt.Errorf("failed to save lockfile: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestUnifiedDiff tests the unified diff output.
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "changed line",
+			old:      "a\nb\nc\n",
+			new:      "a\nB\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "added to empty",
+			old:      "",
+			new:      "a\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:     "missing final newline",
+			old:      "a\n",
+			new:      "a",
+			expected: "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("old", "new", tt.old, tt.new)
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestDiffAndResetRule tests showing and discarding local edits to an installed rule.
+func TestDiffAndResetRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	sourcePath := filepath.Join(tempDir, "rule.mdc")
+	if err := os.WriteFile(sourcePath, []byte("first\nsecond\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write test rule: %v", err)
+	}
+	if err := AddRuleByReference(cursorDir, sourcePath); err != nil {
+		t.Fatalf("AddRuleByReference returned error: %v", err)
+	}
+
+	// The source can disappear, the installed content is in the cache
+	if err := os.Remove(sourcePath); err != nil {
+		t.Fatalf("Failed to remove source rule: %v", err)
+	}
+
+	ruleKey := generateRuleKey(sourcePath)
+	installedPath := filepath.Join(cursorDir, ruleKey+".mdc")
+	if err := os.WriteFile(installedPath, []byte("first\nedited\n"), 0o644); err != nil {
+		t.Fatalf("Failed to edit installed rule: %v", err)
+	}
+
+	diff, err := DiffRule(context.Background(), cursorDir, ruleKey)
+	if err != nil {
+		t.Fatalf("DiffRule returned error: %v", err)
+	}
+	if !strings.Contains(diff, "-second\n+edited\n") {
+		t.Errorf("Expected diff to show the edit, got:\n%s", diff)
+	}
+
+	if err := ResetRule(context.Background(), cursorDir, ruleKey); err != nil {
+		t.Fatalf("ResetRule returned error: %v", err)
+	}
+	data, err := os.ReadFile(installedPath)
+	if err != nil {
+		t.Fatalf("Failed to read reset rule: %v", err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("Expected pristine content after reset, got %q", data)
+	}
+
+	diff, err = DiffRule(context.Background(), cursorDir, ruleKey)
+	if err != nil {
+		t.Fatalf("DiffRule returned error: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("Expected no diff after reset, got:\n%s", diff)
+	}
+
+	if _, err := DiffRule(context.Background(), cursorDir, "no-such-rule"); !IsRuleNotFoundError(err) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxRuleSize is the largest rule file accepted unless configured otherwise.
+const DefaultMaxRuleSize = 1 << 20
+
+// MaxRuleSize is the largest rule file, in bytes, that is downloaded. It is set from
+// download.maxRuleSize in the user config.
+var MaxRuleSize int64 = DefaultMaxRuleSize
+
+// DownloadConfig holds the "download" settings of the user config.
+type DownloadConfig struct {
+	// Largest rule file accepted, in bytes
+	MaxRuleSize int64 `json:"maxRuleSize,omitempty"`
+}
+
+// LoadDownloadConfig sets the size limit of downloaded rules from the download settings
+// of the user config.
+func LoadDownloadConfig() error {
+	config, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+	if config.Download.MaxRuleSize < 0 {
+		return fmt.Errorf("invalid download.maxRuleSize: %d is negative", config.Download.MaxRuleSize)
+	}
+
+	MaxRuleSize = DefaultMaxRuleSize
+	if config.Download.MaxRuleSize > 0 {
+		MaxRuleSize = config.Download.MaxRuleSize
+	}
+	return nil
+}
+
+// maxResponseSize bounds any response that is read into memory, including API listings
+// and share files, which can be much larger than a single rule.
+const maxResponseSize = 32 << 20
+
+// readAPIResponse reads an API response of at most maxResponseSize bytes.
+func readAPIResponse(resp *http.Response, sourceURL string) ([]byte, error) {
+	return readLimitedBody(resp, sourceURL, maxResponseSize)
+}
+
+// readRuleDownload reads a downloaded rule, which must be text of at most MaxRuleSize bytes.
+func readRuleDownload(resp *http.Response, sourceURL string) ([]byte, error) {
+	return readTextDownload(resp, sourceURL, MaxRuleSize)
+}
+
+// readLimitedBody reads a response body of at most limit bytes.
+func readLimitedBody(resp *http.Response, sourceURL string, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, tooLargeError(sourceURL, limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, tooLargeError(sourceURL, limit)
+	}
+	return body, nil
+}
+
+// tooLargeError reports a download over its size limit.
+func tooLargeError(sourceURL string, limit int64) error {
+	return &ErrInvalidDownload{
+		URL:    redactSecrets(sourceURL),
+		Reason: fmt.Sprintf("larger than the %d byte limit (set download.maxRuleSize to raise it)", limit),
+	}
+}
+
+// readTextDownload reads a downloaded rule or share file and checks that it is text of at
+// most limit bytes, rejecting binary files and HTML pages.
+func readTextDownload(resp *http.Response, sourceURL string, limit int64) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if isHTMLMediaType(mediaType) {
+		return nil, htmlPageError(sourceURL)
+	}
+	if !isTextMediaType(mediaType) {
+		return nil, &ErrInvalidDownload{
+			URL:    redactSecrets(sourceURL),
+			Reason: fmt.Sprintf("content type %s is not text", mediaType),
+		}
+	}
+
+	body, err := readLimitedBody(resp, sourceURL, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// The content type can't be trusted for octet-stream or a missing header, so look too
+	if looksLikeHTML(body) {
+		return nil, htmlPageError(sourceURL)
+	}
+	if bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body) {
+		return nil, &ErrInvalidDownload{URL: redactSecrets(sourceURL), Reason: "binary content, not a text file"}
+	}
+	return body, nil
+}
+
+// isHTMLMediaType reports whether a media type is a web page.
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// isTextMediaType reports whether a media type may hold a rule or share file. Files GitHub
+// doesn't recognize are served as application/octet-stream, so their content decides.
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case mediaType == "", strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/octet-stream", mediaType == "application/json":
+		return true
+	}
+	return false
+}
+
+// looksLikeHTML reports whether content is an HTML document.
+func looksLikeHTML(content []byte) bool {
+	start := bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(start) > 64 {
+		start = start[:64]
+	}
+	lower := bytes.ToLower(start)
+	return bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html"))
+}
+
+// htmlPageError reports an HTML page where a file was expected. For GitHub page URLs it
+// names the raw URL that serves the file itself.
+func htmlPageError(sourceURL string) error {
+	reason := "got an HTML page instead of a file"
+	if host, owner, repo, gitRef, path, ok := parseGitHubBlobURL(sourceURL); ok {
+		reason = fmt.Sprintf("this is a GitHub page, not the file; use %s/%s/%s/%s/%s",
+			host.RawURL, owner, repo, gitRef, path)
+	} else if findGitHubWebHost(sourceURL) != nil {
+		reason = "this is a GitHub page, not the file; use the file's raw URL"
+	}
+	return &ErrInvalidDownload{URL: redactSecrets(sourceURL), Reason: reason}
+}
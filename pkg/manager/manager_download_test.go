@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadTextDownload tests rejecting oversized, binary and HTML downloads.
+func TestReadTextDownload(t *testing.T) {
+	const limit = 64
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		contentLength int64
+		expectError   bool
+		expectReason  string
+	}{
+		{name: "plain text", contentType: "text/plain; charset=utf-8", body: "# Rule\n"},
+		{name: "no content type", body: "# Rule\n"},
+		{name: "octet-stream text", contentType: "application/octet-stream", body: "---\ndescription: x\n---\n"},
+		{name: "json", contentType: "application/json", body: `{"rules": []}`},
+		{name: "html content type", contentType: "text/html; charset=utf-8", body: "# Rule\n", expectError: true, expectReason: "HTML page"},
+		{name: "html body", contentType: "text/plain", body: "\n  <!DOCTYPE html><html></html>", expectError: true, expectReason: "HTML page"},
+		{name: "image", contentType: "image/png", body: "\x89PNG", expectError: true, expectReason: "not text"},
+		{name: "nul bytes", contentType: "application/octet-stream", body: "rule\x00\x01", expectError: true, expectReason: "binary"},
+		{name: "invalid utf-8", body: "rule \xff\xfe", expectError: true, expectReason: "binary"},
+		{name: "too large", body: strings.Repeat("a", limit+1), contentLength: -1, expectError: true, expectReason: "limit"},
+		{name: "declared too large", body: "a", contentLength: limit + 1, expectError: true, expectReason: "limit"},
+		{name: "exactly the limit", body: strings.Repeat("a", limit)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{},
+				Body:          io.NopCloser(strings.NewReader(tt.body)),
+				ContentLength: tt.contentLength,
+			}
+			if tt.contentType != "" {
+				resp.Header.Set("Content-Type", tt.contentType)
+			}
+
+			body, err := readTextDownload(resp, "https://example.com/rule.mdc", limit)
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("readTextDownload returned error: %v", err)
+				}
+				if string(body) != tt.body {
+					t.Errorf("Expected body %q, got %q", tt.body, body)
+				}
+				return
+			}
+
+			var downloadErr *ErrInvalidDownload
+			if !errors.As(err, &downloadErr) {
+				t.Fatalf("Expected ErrInvalidDownload, got %v", err)
+			}
+			if !strings.Contains(downloadErr.Reason, tt.expectReason) {
+				t.Errorf("Expected reason mentioning %q, got %q", tt.expectReason, downloadErr.Reason)
+			}
+		})
+	}
+}
+
+// TestHTMLPageError tests pointing GitHub page URLs at the raw file.
+func TestHTMLPageError(t *testing.T) {
+	err := htmlPageError("https://github.com/owner/repo/blob/main/share.json")
+	expected := "use https://raw.githubusercontent.com/owner/repo/main/share.json"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain %q, got: %v", expected, err)
+	}
+
+	err = htmlPageError("https://github.com/owner/repo")
+	if !strings.Contains(err.Error(), "GitHub page") {
+		t.Errorf("Expected error to name a GitHub page, got: %v", err)
+	}
+
+	err = htmlPageError("https://example.com/share.json")
+	if strings.Contains(err.Error(), "GitHub") {
+		t.Errorf("Expected no GitHub hint for another host, got: %v", err)
+	}
+}
+
+// TestFetchGitHubRawSizeLimit tests that rules over MaxRuleSize are rejected without being
+// cached and that the limit can be raised in the user config.
+func TestFetchGitHubRawSizeLimit(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	content := strings.Repeat("rule line\n", 20)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/owner/repo/main/big.mdc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(content))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	configPath := filepath.Join(tempDir, "config.json")
+	t.Setenv(UserConfigPathEnv, configPath)
+	oldMax := MaxRuleSize
+	defer func() {
+		MaxRuleSize = oldMax
+	}()
+
+	if err := os.WriteFile(configPath, []byte(`{"download": {"maxRuleSize": 100}}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := LoadDownloadConfig(); err != nil {
+		t.Fatalf("LoadDownloadConfig returned error: %v", err)
+	}
+	_, err := fetchGitHubRaw(ctx, githubDotCom, "owner", "repo", "main", "big.mdc")
+	if !IsInvalidDownloadError(err) {
+		t.Errorf("Expected ErrInvalidDownload for a %d byte rule, got %v", len(content), err)
+	}
+	if entry := loadHTTPCacheEntry(githubDotCom.RawURL + "/owner/repo/main/big.mdc"); entry != nil {
+		t.Error("Expected the rejected rule not to be cached")
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"download": {"maxRuleSize": 1000}}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := LoadDownloadConfig(); err != nil {
+		t.Fatalf("LoadDownloadConfig returned error: %v", err)
+	}
+	data, err := fetchGitHubRaw(ctx, githubDotCom, "owner", "repo", "main", "big.mdc")
+	if err != nil {
+		t.Fatalf("fetchGitHubRaw returned error: %v", err)
+	}
+	if string(data) != content {
+		t.Error("Downloaded content differs from the served rule")
+	}
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProjectLockFileName is the file used to serialize cursor-rules invocations in a project.
+// It lives in the .cursor directory so it doesn't move when the lockfile location changes.
+const ProjectLockFileName = "cursor-rules.lck"
+
+// projectLockPollInterval is how often a waiting process retries the lock.
+const projectLockPollInterval = 100 * time.Millisecond
+
+// errLockHeld is returned by tryLockFile when another process holds the lock.
+var errLockHeld = errors.New("lock is held by another process")
+
+// ProjectLock is an advisory lock held for the duration of a command.
+type ProjectLock struct {
+	file *os.File
+	path string
+}
+
+// getProjectLockPath returns the path to the advisory lock file.
+func getProjectLockPath(cursorDir string) string {
+	return filepath.Join(filepath.Dir(cursorDir), ProjectLockFileName)
+}
+
+// AcquireProjectLock takes the advisory lock for the project, waiting up to timeout
+// for another cursor-rules process to finish. A zero timeout fails immediately.
+func AcquireProjectLock(cursorDir string, timeout time.Duration) (*ProjectLock, error) {
+	lockPath := getProjectLockPath(cursorDir)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := tryLockFile(lockPath)
+		if err == nil {
+			Debugf("AcquireProjectLock: acquired %s", lockPath)
+			return &ProjectLock{file: file, path: lockPath}, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, &ErrLockBusy{Path: lockPath, Waited: timeout}
+		}
+		time.Sleep(projectLockPollInterval)
+	}
+}
+
+// Release releases the advisory lock.
+func (l *ProjectLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlockFile(l.file, l.path)
+	l.file = nil
+	return err
+}
@@ -0,0 +1,35 @@
+//go:build !unix
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// tryLockFile creates the lock file exclusively. Without flock the file itself is
+// the lock, so a process that crashes leaves it behind and it must be removed by hand.
+func tryLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, errLockHeld
+		}
+		return nil, err
+	}
+
+	// Record the owner to help diagnose stale locks
+	_, _ = file.WriteString(strconv.Itoa(os.Getpid()))
+	return file, nil
+}
+
+// unlockFile closes and removes the lock file.
+func unlockFile(file *os.File, path string) error {
+	file.Close()
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package manager
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestAcquireProjectLock tests that a second holder is rejected until the lock is released.
+func TestAcquireProjectLock(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	first, err := AcquireProjectLock(cursorDir, 0)
+	if err != nil {
+		t.Fatalf("AcquireProjectLock returned error: %v", err)
+	}
+
+	if _, err := AcquireProjectLock(cursorDir, 0); !IsLockBusyError(err) {
+		t.Fatalf("Expected ErrLockBusy while the lock is held, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+
+	second, err := AcquireProjectLock(cursorDir, 0)
+	if err != nil {
+		t.Fatalf("AcquireProjectLock after release returned error: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+}
+
+// TestLockFileSave_Atomic tests that saving leaves no temporary files behind.
+func TestLockFileSave_Atomic(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	lock := &LockFile{Rules: []RuleSource{{Key: "test-rule", SourceType: SourceTypeBuiltIn}}}
+	for i := 0; i < 3; i++ {
+		if err := lock.Save(tempDir); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// tryLockFile opens the lock file and takes an exclusive flock without blocking.
+// The kernel releases the lock if the process dies, so stale locks are not possible.
+func tryLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLockHeld
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// unlockFile releases the flock and closes the lock file.
+func unlockFile(file *os.File, _ string) error {
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return file.Close()
+}
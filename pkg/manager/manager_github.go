@@ -6,58 +6,46 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 )
 
 // handleGitHubBlob handles a GitHub blob URL reference.
 func handleGitHubBlob(ctx context.Context, cursorDir, ref string) (RuleSource, error) {
-	// Parse the URL to extract owner, repo, commit/branch, and path
-	matches := githubBlobPattern.FindStringSubmatch(ref)
-	if len(matches) != 5 {
-		return RuleSource{}, fmt.Errorf("invalid GitHub URL format: %s", ref)
-	}
-
-	owner := matches[1]
-	repo := matches[2]
-	gitRef := matches[3]
-	path := matches[4]
-
-	Debugf("handleGitHubBlob: parsed URL - owner='%s', repo='%s', gitRef='%s', path='%s'",
-		owner, repo, gitRef, path)
+	return handleGitHubBlobAtCommit(ctx, cursorDir, ref, "")
+}
 
+// handleGitHubBlobAtCommit handles a GitHub blob URL reference, downloading the file at
+// commit instead of the URL's ref when commit is set. Files matched by one glob listing
+// are installed this way, so they all come from the commit the listing was made at.
+func handleGitHubBlobAtCommit(ctx context.Context, cursorDir, ref, commit string) (RuleSource, error) {
 	// Generate the rule key (owner-repo-filename)
-	key := generateRuleKey(ref)
-	Debugf("handleGitHubBlob: generated key='%s'", key)
-
-	// Create the raw URL for downloading the file
-	rawURL := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, gitRef, path)
-	Debugf("handleGitHubBlob: using raw URL='%s'", rawURL)
-
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
-	if err != nil {
-		return RuleSource{}, fmt.Errorf("failed to create request for GitHub file: %w", err)
-	}
+	return installGitHubBlob(ctx, cursorDir, ref, commit, generateRuleKey(ref))
+}
 
-	// Download the file
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		Debugf("handleGitHubBlob: HTTP request failed: %v", err)
-		return RuleSource{}, fmt.Errorf("failed to download GitHub file: %w", err)
+// installGitHubBlob installs a GitHub blob URL under the given rule key, downloading the file
+// at commit instead of the URL's ref when commit is set.
+func installGitHubBlob(ctx context.Context, cursorDir, ref, commit, key string) (RuleSource, error) {
+	// Parse the URL to extract host, owner, repo, commit/branch, and path
+	host, owner, repo, gitRef, path, ok := parseGitHubBlobURL(ref)
+	if !ok {
+		return RuleSource{}, fmt.Errorf("invalid GitHub URL format: %s", ref)
 	}
-	defer resp.Body.Close()
 
-	Debugf("handleGitHubBlob: HTTP status code: %d %s", resp.StatusCode, resp.Status)
+	Debugf("handleGitHubBlob: parsed URL - host='%s', owner='%s', repo='%s', gitRef='%s', path='%s', key='%s'",
+		host.WebURL, owner, repo, gitRef, path, key)
 
-	if resp.StatusCode != http.StatusOK {
-		return RuleSource{}, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	if err := checkRuleKeyAvailable(cursorDir, key, ref); err != nil {
+		return RuleSource{}, err
 	}
 
-	// Read the content
-	content, err := io.ReadAll(resp.Body)
+	// Download the file
+	fetchRef := gitRef
+	if commit != "" {
+		fetchRef = commit
+	}
+	content, err := fetchGitHubRaw(ctx, host, owner, repo, fetchRef, path)
 	if err != nil {
-		return RuleSource{}, fmt.Errorf("failed to read GitHub file content: %w", err)
+		return RuleSource{}, err
 	}
 
 	Debugf("handleGitHubBlob: successfully read %d bytes", len(content))
@@ -71,7 +59,7 @@ func handleGitHubBlob(ctx context.Context, cursorDir, ref string) (RuleSource, e
 		return RuleSource{}, fmt.Errorf("failed preparing directory for rule '%s': %w", key, err)
 	}
 
-	err = os.WriteFile(targetPath, content, 0o644)
+	err = writeRuleFile(targetPath, content, 0o644)
 	if err != nil {
 		return RuleSource{}, fmt.Errorf("failed to write rule file: %w", err)
 	}
@@ -84,10 +72,13 @@ func handleGitHubBlob(ctx context.Context, cursorDir, ref string) (RuleSource, e
 	} else {
 		gitRefType = "branch="
 		// For branches, we should resolve the commit hash for reproducibility
-		resolvedCommit, err = getHeadCommitForBranch(ctx, owner, repo, gitRef)
+		resolvedCommit = commit
+		if resolvedCommit == "" {
+			resolvedCommit, err = getHeadCommitForBranch(ctx, host, owner, repo, gitRef)
+		}
 		if err != nil {
 			// Not a fatal error, but log it
-			fmt.Printf("Warning: Could not resolve commit hash for branch %s: %v\n", gitRef, err)
+			warnf(ctx, "Could not resolve commit hash for branch %s: %v", gitRef, err)
 		}
 	}
 
@@ -97,7 +88,8 @@ func handleGitHubBlob(ctx context.Context, cursorDir, ref string) (RuleSource, e
 		SourceType: SourceTypeGitHubFile,
 		Reference:  ref, // The original GitHub URL
 		GitRef:     gitRefType + gitRef,
-		LocalFiles: []string{targetPath},
+		LocalFiles: []string{key + ".mdc"},
+		SourceURL:  ref,
 	}
 
 	// Add resolved commit if available
@@ -112,13 +104,73 @@ func handleGitHubBlob(ctx context.Context, cursorDir, ref string) (RuleSource, e
 	return result, nil
 }
 
+// installFirstGitHubBlob installs the first of the blob URLs that can be downloaded with
+// install and returns its index. A rate limit ends the search with ErrGitHubRateLimit, trying
+// the remaining URLs would only hit it again, and so does a key taken by another rule. Other
+// failures move on to the next URL; when none works the index is -1 and the error nil, and
+// the caller reports the rule as not found.
+func installFirstGitHubBlob(ctx context.Context, cursorDir string,
+	install func(ctx context.Context, cursorDir, blobURL string) (RuleSource, error), urls ...string,
+) (RuleSource, int, error) {
+	for i, blobURL := range urls {
+		Debugf("installFirstGitHubBlob: trying URL: %s\n", blobURL)
+		rule, err := install(ctx, cursorDir, blobURL)
+		if err == nil {
+			return rule, i, nil
+		}
+		if IsGitHubRateLimitError(err) || IsRuleKeyConflictError(err) {
+			return RuleSource{}, -1, err
+		}
+		Debugf("installFirstGitHubBlob: %s failed with error: %v\n", blobURL, err)
+	}
+	return RuleSource{}, -1, nil
+}
+
+// fetchGitHubRaw downloads a file from the raw content host of a GitHub host at the given git ref.
+func fetchGitHubRaw(ctx context.Context, host *GitHubHost, owner, repo, gitRef, path string) ([]byte, error) {
+	// Create the raw URL for downloading the file
+	rawURL := fmt.Sprintf("%s/%s/%s/%s/%s", host.RawURL, owner, repo, gitRef, path)
+	Debugf("fetchGitHubRaw: using raw URL='%s'", rawURL)
+
+	// Create request with context
+	req, err := newGitHubRequest(ctx, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for GitHub file: %w", err)
+	}
+
+	// Download the file, rejecting web pages, binary files and oversized rules
+	resp, err := doCachedRequest(req, sendGitHubRequest, readRuleDownload)
+	if err != nil {
+		Debugf("fetchGitHubRaw: HTTP request failed: %v", err)
+		if IsInvalidDownloadError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to download GitHub file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	Debugf("fetchGitHubRaw: HTTP status code: %d %s", resp.StatusCode, resp.Status)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	// The content was checked when it was downloaded
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub file content: %w", err)
+	}
+
+	return content, nil
+}
+
 // getHeadCommitForBranch fetches the latest commit hash for a branch.
-func getHeadCommitForBranch(ctx context.Context, owner, repo, branch string) (string, error) {
+func getHeadCommitForBranch(ctx context.Context, host *GitHubHost, owner, repo, branch string) (string, error) {
 	// Use the GitHub API to get the branch info
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches/%s", owner, repo, branch)
+	url := fmt.Sprintf("%s/repos/%s/%s/branches/%s", host.APIURL, owner, repo, branch)
 
 	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := newGitHubRequest(ctx, url)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request for GitHub API: %w", err)
 	}
@@ -127,7 +179,7 @@ func getHeadCommitForBranch(ctx context.Context, owner, repo, branch string) (st
 	req.Header.Add("Accept", "application/vnd.github.v3+json")
 
 	// Send the request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doGitHubRequest(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get branch information: %w", err)
 	}
@@ -151,10 +203,78 @@ func getHeadCommitForBranch(ctx context.Context, owner, repo, branch string) (st
 	return response.Commit.SHA, nil
 }
 
-// handleGitHubDir handles a GitHub directory URL reference.
-func handleGitHubDir(cursorDir, ref string) (RuleSource, error) {
-	// We don't support directories yet
-	return RuleSource{}, fmt.Errorf("GitHub directory references are not yet supported: %s", ref)
+// getDefaultBranch fetches the name of a repository's default branch.
+func getDefaultBranch(ctx context.Context, host *GitHubHost, owner, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", host.APIURL, owner, repo)
+
+	req, err := newGitHubRequest(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for GitHub API: %w", err)
+	}
+	req.Header.Add("Accept", "application/vnd.github.v3+json")
+
+	resp, err := doGitHubRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository information: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	var response struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	if response.DefaultBranch == "" {
+		return "", fmt.Errorf("GitHub API response for %s/%s has no default branch", owner, repo)
+	}
+	return response.DefaultBranch, nil
+}
+
+// listGitHubTags returns the names of all tags in a repository.
+func listGitHubTags(ctx context.Context, host *GitHubHost, owner, repo string) ([]string, error) {
+	var tags []string
+
+	// The API returns at most 100 tags per page
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=100&page=%d", host.APIURL, owner, repo, page)
+
+		req, err := newGitHubRequest(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request for GitHub API: %w", err)
+		}
+		req.Header.Add("Accept", "application/vnd.github.v3+json")
+
+		resp, err := doGitHubRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+		}
+
+		var response []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub API response: %w", err)
+		}
+
+		for _, tag := range response {
+			tags = append(tags, tag.Name)
+		}
+		if len(response) < 100 {
+			return tags, nil
+		}
+	}
 }
 
 // handleLocalFile is now moved to manager_local_handlers.go
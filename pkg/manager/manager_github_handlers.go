@@ -17,26 +17,26 @@ func handleUsernameRule(ctx context.Context, cursorDir, ref string) (RuleSource,
 	Debugf("handleUsernameRule: username='%s', ruleName='%s'\n", username, ruleName)
 
 	// Try to find it in username/cursor-rules-collection repo at root level only
-	githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s.mdc", username, ruleName)
+	githubURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s.mdc", username, ruleName))
 	Debugf("handleUsernameRule: trying URL: %s\n", githubURL)
 
-	rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-
-	if err == nil {
-		// Found in the cursor-rules-collection repo
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = ref // Store the original reference
-		Debugf("handleUsernameRule: Found rule at primary URL\n")
-		return rule, nil
+	rule, found, err := installFirstGitHubBlob(ctx, cursorDir, handleGitHubBlob, githubURL)
+	if err != nil {
+		return RuleSource{}, err
 	}
 
-	Debugf("handleUsernameRule: Primary URL failed with error: %v\n", err)
-
 	// No subfolder fallback for username/rule format
 	// This is intentional - username/rule should only look for the rule at the root level
 	// If users want a nested rule, they should use username/path/rule format
+	if found < 0 {
+		return RuleSource{}, ruleNotFoundError(ref, "rule not found in username/cursor-rules-collection: %s", ref)
+	}
 
-	return RuleSource{}, fmt.Errorf("rule not found in username/cursor-rules-collection: %s", ref)
+	// Found in the cursor-rules-collection repo
+	rule.SourceType = SourceTypeGitHubShorthand
+	rule.Reference = ref // Store the original reference
+	Debugf("handleUsernameRule: Found rule at primary URL\n")
+	return rule, nil
 }
 
 // handleUsernamePathRule handles a reference in the username/path/rule format.
@@ -49,40 +49,29 @@ func handleUsernamePathRule(ctx context.Context, cursorDir, ref string) (RuleSou
 
 	Debugf("handleUsernamePathRule: username='%s', pathParts=%v\n", username, pathParts)
 
-	// First, try to interpret it as username/path/to/rule in cursor-rules-collection
-	if len(pathParts) >= 1 {
-		// Extract the last part as the rule name and construct the path
-		ruleName := pathParts[len(pathParts)-1]
-		pathToRule := strings.Join(pathParts[:len(pathParts)-1], "/")
-
-		// If the rule already has .mdc extension, don't add it again
-		ruleFile := ruleName
-		if !strings.HasSuffix(ruleName, ".mdc") {
-			ruleFile = ruleName + ".mdc"
-		}
+	// Candidate locations in the order they are tried, with the source type each one records
+	var urls []string
+	var sourceTypes []SourceType
 
-		var githubURL string
-		if pathToRule == "" {
-			githubURL = fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s",
-				username, ruleFile)
-		} else {
-			githubURL = fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s/%s",
-				username, pathToRule, ruleFile)
-		}
-
-		Debugf("handleUsernamePathRule: trying URL: %s\n", githubURL)
-
-		rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-		if err == nil {
-			// Found in the cursor-rules-collection repo
-			rule.SourceType = SourceTypeGitHubShorthand
-			rule.Reference = ref // Store the original reference
-			Debugf("handleUsernamePathRule: Found rule in cursor-rules-collection\n")
-			return rule, nil
-		}
+	// First, try to interpret it as username/path/to/rule in cursor-rules-collection
+	// Extract the last part as the rule name and construct the path
+	ruleName := pathParts[len(pathParts)-1]
+	pathToRule := strings.Join(pathParts[:len(pathParts)-1], "/")
+
+	// If the rule already has .mdc extension, don't add it again
+	ruleFile := ruleName
+	if !strings.HasSuffix(ruleName, ".mdc") {
+		ruleFile = ruleName + ".mdc"
+	}
 
-		Debugf("handleUsernamePathRule: cursor-rules-collection URL failed with error: %v\n", err)
+	if pathToRule == "" {
+		urls = append(urls, shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s",
+			username, ruleFile)))
+	} else {
+		urls = append(urls, shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s/%s",
+			username, pathToRule, ruleFile)))
 	}
+	sourceTypes = append(sourceTypes, SourceTypeGitHubShorthand)
 
 	// As a fallback, attempt to interpret it as username/repo/path/to/rule.mdc
 	if len(pathParts) >= 2 {
@@ -94,55 +83,40 @@ func handleUsernamePathRule(ctx context.Context, cursorDir, ref string) (RuleSou
 			remainingPath += ".mdc"
 		}
 
-		githubURL := fmt.Sprintf("https://github.com/%s/%s/blob/main/%s",
-			username, repoName, remainingPath)
-
-		Debugf("handleUsernamePathRule: trying fallback URL (any repo): %s\n", githubURL)
-
-		rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-		if err == nil {
-			// Found in the other repo
-			rule.SourceType = SourceTypeGitHubRepoPath
-			rule.Reference = ref // Store the original reference
-			Debugf("handleUsernamePathRule: Found rule in other repo\n")
-			return rule, nil
-		}
-
-		Debugf("handleUsernamePathRule: fallback URL failed with error: %v\n", err)
+		urls = append(urls, shorthandBlobURL(fmt.Sprintf("%s/%s/blob/main/%s",
+			username, repoName, remainingPath)))
+		sourceTypes = append(sourceTypes, SourceTypeGitHubRepoPath)
 	}
 
 	// Extra fallback: Try to handle the special case of "username/path/rule"
 	// This is specifically for fireharp/monorepo/monorepo type paths
-	if len(pathParts) >= 1 {
-		// Try to look for the file at "path/rule.mdc" in cursor-rules-collection
-		// This creates a URL like: https://github.com/fireharp/cursor-rules-collection/blob/main/monorepo/monorepo.mdc
-
-		// Join all path parts with /
-		fullPath := strings.Join(pathParts, "/")
-
-		// If the last part already has .mdc extension, don't add it again
-		if !strings.HasSuffix(fullPath, ".mdc") {
-			fullPath += ".mdc"
-		}
+	// Try to look for the file at "path/rule.mdc" in cursor-rules-collection
+	// This creates a URL like: https://github.com/fireharp/cursor-rules-collection/blob/main/monorepo/monorepo.mdc
 
-		githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s",
-			username, fullPath)
+	// Join all path parts with /
+	fullPath := strings.Join(pathParts, "/")
 
-		Debugf("handleUsernamePathRule: trying second fallback URL (nested structure): %s\n", githubURL)
+	// If the last part already has .mdc extension, don't add it again
+	if !strings.HasSuffix(fullPath, ".mdc") {
+		fullPath += ".mdc"
+	}
 
-		rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-		if err == nil {
-			// Found in the cursor-rules-collection repo with the nested structure
-			rule.SourceType = SourceTypeGitHubShorthand
-			rule.Reference = ref // Store the original reference
-			Debugf("handleUsernamePathRule: Found rule in cursor-rules-collection nested structure\n")
-			return rule, nil
-		}
+	urls = append(urls, shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s",
+		username, fullPath)))
+	sourceTypes = append(sourceTypes, SourceTypeGitHubShorthand)
 
-		Debugf("handleUsernamePathRule: second fallback URL failed with error: %v\n", err)
+	rule, found, err := installFirstGitHubBlob(ctx, cursorDir, handleGitHubBlob, urls...)
+	if err != nil {
+		return RuleSource{}, err
+	}
+	if found < 0 {
+		return RuleSource{}, ruleNotFoundError(ref, "rule not found in any repo: %s", ref)
 	}
 
-	return RuleSource{}, fmt.Errorf("rule not found in any repo: %s", ref)
+	rule.SourceType = sourceTypes[found]
+	rule.Reference = ref // Store the original reference
+	Debugf("handleUsernamePathRule: Found rule at %s\n", urls[found])
+	return rule, nil
 }
 
 // handleUsernameRuleWithSha handles a reference in the username/rule:sha format.
@@ -153,66 +127,83 @@ func handleUsernameRuleWithSha(ctx context.Context, cursorDir, ref string) (Rule
 	}
 
 	// Build GitHub URL with specific commit
-	githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/%s/%s.mdc",
-		username, sha, ruleName)
-
-	rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo at specific commit
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = ref // Store the original reference
-		rule.GitRef = "commit=" + sha
-		return rule, nil
-	}
+	githubURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/%s/%s.mdc",
+		username, sha, ruleName))
 
 	// Try in a subdirectory as well
-	githubURL = fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/%s/%s/%s.mdc",
-		username, sha, ruleName, ruleName)
+	subdirURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/%s/%s/%s.mdc",
+		username, sha, ruleName, ruleName))
 
-	rule, err = handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo in a subdirectory at specific commit
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = ref // Store the original reference
-		rule.GitRef = "commit=" + sha
-		return rule, nil
+	rule, found, err := installFirstGitHubBlob(ctx, cursorDir, handleGitHubBlob, githubURL, subdirURL)
+	if err != nil {
+		return RuleSource{}, err
+	}
+	if found < 0 {
+		return RuleSource{}, ruleNotFoundError(ref, "rule not found in username/cursor-rules-collection at commit %s: %s", sha, ref)
 	}
 
-	return RuleSource{}, fmt.Errorf("rule not found in username/cursor-rules-collection at commit %s: %s", sha, ref)
+	// Found in the cursor-rules-collection repo at specific commit
+	rule.SourceType = SourceTypeGitHubShorthand
+	rule.Reference = ref // Store the original reference
+	rule.GitRef = "commit=" + sha
+	return rule, nil
 }
 
-// handleUsernameRuleWithTag handles a reference in the username/rule@tag format.
+// handleUsernameRuleWithTag handles a reference in the username/rule@tag format. The tag may
+// also be a version range like ^1.2 or ~1.4.0, which resolves to the highest matching tag.
 func handleUsernameRuleWithTag(ctx context.Context, cursorDir, ref string) (RuleSource, error) {
 	username, ruleName, tag, ok := parseUsernameRuleWithTag(ref)
 	if !ok {
 		return RuleSource{}, fmt.Errorf("invalid username/rule@tag format: %s", ref)
 	}
 
-	// Build GitHub URL with specific tag
-	githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/%s/%s.mdc",
-		username, tag, ruleName)
+	versionRange := ""
+	if isSemverRange(tag) {
+		if _, ok := parseSemverRange(tag); !ok {
+			return RuleSource{}, fmt.Errorf("invalid version range %s: %s", tag, ref)
+		}
 
-	rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo at specific tag
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = ref // Store the original reference
-		rule.GitRef = "tag=" + tag
-		return rule, nil
+		tags, err := listGitHubTags(ctx, shorthandGitHubHost(), username, "cursor-rules-collection")
+		if err != nil {
+			return RuleSource{}, fmt.Errorf("failed to list tags of %s/cursor-rules-collection: %w", username, err)
+		}
+
+		versionRange, tag = tag, highestMatchingTag(tags, tag)
+		if tag == "" {
+			return RuleSource{}, fmt.Errorf("no tag of %s/cursor-rules-collection matches %s", username, versionRange)
+		}
+		Debugf("Resolved version range %s to tag %s\n", versionRange, tag)
 	}
 
+	// A range gets a key of its own, it must not take the place of the plain rule
+	install := handleGitHubBlob
+	if versionRange != "" {
+		key := generateRuleKey(ref)
+		install = func(ctx context.Context, cursorDir, githubURL string) (RuleSource, error) {
+			return installGitHubBlob(ctx, cursorDir, githubURL, "", key)
+		}
+	}
+
+	// Build GitHub URL with specific tag
+	githubURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/%s/%s.mdc",
+		username, tag, ruleName))
+
 	// Try in a subdirectory as well
-	githubURL = fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/%s/%s/%s.mdc",
-		username, tag, ruleName, ruleName)
+	subdirURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/%s/%s/%s.mdc",
+		username, tag, ruleName, ruleName))
 
-	rule, err = handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo in a subdirectory at specific tag
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = ref // Store the original reference
-		rule.GitRef = "tag=" + tag
-		return rule, nil
+	rule, found, err := installFirstGitHubBlob(ctx, cursorDir, install, githubURL, subdirURL)
+	if err != nil {
+		return RuleSource{}, err
+	}
+	if found < 0 {
+		return RuleSource{}, ruleNotFoundError(ref, "rule not found in username/cursor-rules-collection at tag %s: %s", tag, ref)
 	}
 
-	return RuleSource{}, fmt.Errorf("rule not found in username/cursor-rules-collection at tag %s: %s", tag, ref)
+	// Found in the cursor-rules-collection repo at specific tag
+	rule.SourceType = SourceTypeGitHubShorthand
+	rule.Reference = ref // Store the original reference
+	rule.GitRef = "tag=" + tag
+	rule.VersionRange = versionRange
+	return rule, nil
 }
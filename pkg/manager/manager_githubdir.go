@@ -0,0 +1,326 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// handleGitHubDir handles a GitHub tree URL reference. Every .mdc file below the directory
+// is installed under the rule key, keeping its subfolders, and the whole directory is
+// recorded as one rule so it can be upgraded or removed as a unit.
+func handleGitHubDir(ctx context.Context, cursorDir, ref string) (RuleSource, error) {
+	host, owner, repo, gitRef, dir, ok := parseGitHubTreeURL(ref)
+	if !ok {
+		return RuleSource{}, fmt.Errorf("invalid GitHub URL format: %s", ref)
+	}
+
+	Debugf("handleGitHubDir: parsed URL - host='%s', owner='%s', repo='%s', gitRef='%s', dir='%s'",
+		host.WebURL, owner, repo, gitRef, dir)
+
+	// Another rule's files must not be overwritten
+	key := generateRuleKey(ref)
+	if err := checkRuleKeyAvailable(cursorDir, key, ref); err != nil {
+		return RuleSource{}, err
+	}
+
+	// Branches are resolved to a commit, so every file comes from the same snapshot
+	commit := gitRef
+	gitRefType := "commit="
+	if !isGitCommitHash(gitRef) {
+		gitRefType = "branch="
+		var err error
+		commit, err = getHeadCommitForBranch(ctx, host, owner, repo, gitRef)
+		if err != nil {
+			return RuleSource{}, fmt.Errorf("failed to resolve branch %s: %w", gitRef, err)
+		}
+	}
+
+	files, err := listGitHubDirFiles(ctx, host, owner, repo, commit, dir)
+	if err != nil {
+		return RuleSource{}, err
+	}
+
+	contents, err := fetchGitHubDirFiles(ctx, host, owner, repo, commit, dir, files)
+	if err != nil {
+		return RuleSource{}, err
+	}
+
+	Debugf("handleGitHubDir: generated key='%s' for %d files", key, len(files))
+
+	result := RuleSource{
+		Key:        key,
+		SourceType: SourceTypeGitHubDir,
+		Reference:  ref,
+		GitRef:     gitRefType + gitRef,
+		LocalFiles: make([]string, 0, len(files)),
+		SourceURL:  ref,
+		FileSHA256: make(map[string]string, len(files)),
+	}
+	if gitRefType == "branch=" {
+		result.ResolvedCommit = commit
+	}
+
+	for i, file := range files {
+		localFile := key + "/" + file
+		if err := writeRuleFile(ruleFilePath(cursorDir, localFile), contents[i], 0o644); err != nil {
+			return RuleSource{}, fmt.Errorf("failed to write rule file: %w", err)
+		}
+		result.LocalFiles = append(result.LocalFiles, localFile)
+		result.FileSHA256[localFile] = calculateSHA256(contents[i])
+	}
+
+	return result, nil
+}
+
+// listGitHubDirFiles lists the .mdc files below a directory of a repository at a commit,
+// relative to the directory and sorted.
+func listGitHubDirFiles(ctx context.Context, host *GitHubHost, owner, repo, commit, dir string) ([]string, error) {
+	prefix := dir + "/"
+
+	var paths []string
+	entries, truncated, err := listGitHubTree(ctx, host, owner, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		// The tree is too large for a single response; walk the directory instead
+		Debugf("listGitHubDirFiles: tree of %s/%s at %s is truncated, listing directories\n",
+			owner, repo, shortCommit(commit))
+		paths, err = recursivelyListGitHubFiles(ctx, host, owner, repo, commit, dir, nil, "**")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				paths = append(paths, entry.Path)
+			}
+		}
+	}
+
+	var files []string
+	for _, path := range paths {
+		if file, ok := strings.CutPrefix(path, prefix); ok && strings.HasSuffix(file, ".mdc") {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .mdc files found in %s of %s/%s", dir, owner, repo)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// fetchGitHubDirFiles downloads files of a directory at a commit in parallel. The contents
+// are returned in the order of files.
+func fetchGitHubDirFiles(ctx context.Context, host *GitHubHost, owner, repo, commit, dir string, files []string) ([][]byte, error) {
+	contents := make([][]byte, len(files))
+	errs := make([]error, len(files))
+	forEachParallel(len(files), func(i int) {
+		content, err := fetchGitHubRaw(ctx, host, owner, repo, commit, dir+"/"+files[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", files[i], err)
+			return
+		}
+		contents[i] = content
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// ruleFileSources returns a single-file rule for every file of a GitHub directory rule,
+// with the file's blob URL and hash, so each file can be fetched, verified, diffed and
+// merged like a GitHub file rule. Any other rule is returned as it is.
+func ruleFileSources(rule RuleSource) []RuleSource {
+	if rule.SourceType != SourceTypeGitHubDir {
+		return []RuleSource{rule}
+	}
+
+	sourceURL := rule.SourceURL
+	if sourceURL == "" {
+		sourceURL = rule.Reference
+	}
+	host, owner, repo, gitRef, dir, ok := parseGitHubTreeURL(sourceURL)
+	if rule.ResolvedCommit != "" {
+		gitRef = rule.ResolvedCommit
+	}
+
+	fileRules := make([]RuleSource, 0, len(rule.LocalFiles))
+	for _, file := range rule.LocalFiles {
+		fileRule := RuleSource{
+			Key:            rule.Key,
+			SourceType:     SourceTypeGitHubFile,
+			Reference:      rule.Reference,
+			GitRef:         rule.GitRef,
+			LocalFiles:     []string{file},
+			ResolvedCommit: rule.ResolvedCommit,
+			ContentSHA256:  rule.FileSHA256[file],
+			MergeConflict:  rule.MergeConflict,
+		}
+		// Without a source URL fetching fails later with a clear error
+		if ok {
+			fileRule.SourceURL = fmt.Sprintf("%s/%s/%s/blob/%s/%s/%s",
+				host.WebURL, owner, repo, gitRef, dir, strings.TrimPrefix(file, rule.Key+"/"))
+		}
+		fileRules = append(fileRules, fileRule)
+	}
+	return fileRules
+}
+
+// checkUpstreamDirChanged reports whether the .mdc files below a GitHub directory at the
+// head of its branch differ from the files the lockfile records.
+func checkUpstreamDirChanged(ctx context.Context, rule RuleSource) (bool, error) {
+	if !strings.HasPrefix(rule.GitRef, "branch=") {
+		return false, nil
+	}
+
+	host, owner, repo, dir, err := githubRuleLocation(rule)
+	if err != nil {
+		return false, err
+	}
+
+	commit, err := getHeadCommitForBranch(ctx, host, owner, repo, strings.TrimPrefix(rule.GitRef, "branch="))
+	if err != nil {
+		return false, err
+	}
+	if commit == rule.ResolvedCommit {
+		return false, nil
+	}
+	return dirChangedAtCommit(ctx, rule, host, owner, repo, dir, commit)
+}
+
+// dirChangedAtCommit reports whether the .mdc files below a GitHub directory at commit differ
+// from the files the lockfile records. The branch moving doesn't mean the directory changed.
+func dirChangedAtCommit(ctx context.Context, rule RuleSource, host *GitHubHost, owner, repo, dir, commit string) (bool, error) {
+	files, err := listGitHubDirFiles(ctx, host, owner, repo, commit, dir)
+	if err != nil {
+		return false, err
+	}
+	if len(files) != len(rule.FileSHA256) {
+		return true, nil
+	}
+	contents, err := fetchGitHubDirFiles(ctx, host, owner, repo, commit, dir, files)
+	if err != nil {
+		return false, err
+	}
+	for i, file := range files {
+		if rule.FileSHA256[rule.Key+"/"+file] != calculateSHA256(contents[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upgradeGitHubDirRule moves a GitHub directory rule to the head of its branch. Changed
+// files are upgraded like file rules, merging local edits; files added upstream are
+// installed and files removed upstream are deleted. A rule pinned to a commit is only
+// unpinned after confirmation, and then follows the repository's default branch.
+func upgradeGitHubDirRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	host, owner, repo, dir, err := githubRuleLocation(*rule)
+	if err != nil {
+		return err
+	}
+
+	var branch string
+	switch {
+	case strings.HasPrefix(rule.GitRef, "branch="):
+		branch = strings.TrimPrefix(rule.GitRef, "branch=")
+	case strings.HasPrefix(rule.GitRef, "commit="):
+		if err := confirmUnpin(strings.TrimPrefix(rule.GitRef, "commit=")); err != nil {
+			return err
+		}
+		branch, err = getDefaultBranch(ctx, host, owner, repo)
+		if err != nil {
+			return fmt.Errorf("failed to get default branch: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown Git reference type: %s", rule.GitRef)
+	}
+
+	latestCommit, err := getHeadCommitForBranch(ctx, host, owner, repo, branch)
+	if err != nil {
+		return fmt.Errorf("failed to get latest commit: %w", err)
+	}
+	if rule.ResolvedCommit == latestCommit {
+		fmt.Printf("Rule is already at the latest commit (%s) for branch %s\n", shortCommit(latestCommit), branch)
+		return nil
+	}
+
+	files, err := listGitHubDirFiles(ctx, host, owner, repo, latestCommit, dir)
+	if err != nil {
+		return err
+	}
+	contents, err := fetchGitHubDirFiles(ctx, host, owner, repo, latestCommit, dir, files)
+	if err != nil {
+		return fmt.Errorf("failed to download files: %w", err)
+	}
+
+	installed := make(map[string]RuleSource, len(rule.LocalFiles))
+	for _, fileRule := range ruleFileSources(*rule) {
+		installed[fileRule.LocalFiles[0]] = fileRule
+	}
+
+	localFiles := make([]string, 0, len(files))
+	hashes := make(map[string]string, len(files))
+	conflict := false
+	for i, file := range files {
+		localFile := rule.Key + "/" + file
+		fileRule, ok := installed[localFile]
+		if !ok {
+			fmt.Printf("Adding %s\n", localFile)
+			fileRule = RuleSource{Key: rule.Key, LocalFiles: []string{localFile}}
+			// A file no rule tracks may already be at the path. It is handled like local
+			// edits of the new file instead of being overwritten
+			if fileExists(ruleFilePath(cursorDir, localFile)) {
+				fileRule.ContentSHA256 = calculateSHA256(contents[i])
+			}
+		}
+		delete(installed, localFile)
+
+		if err := applyUpgradedContent(ctx, cursorDir, &fileRule, contents[i]); err != nil {
+			return err
+		}
+		localFiles = append(localFiles, localFile)
+		hashes[localFile] = fileRule.ContentSHA256
+		conflict = conflict || fileRule.MergeConflict
+	}
+
+	// Files that are gone upstream are removed, unless that would lose local edits. Such a
+	// file stays part of the rule with its last upstream hash, so it still shows as edited
+	removed := make([]string, 0, len(installed))
+	for localFile := range installed {
+		removed = append(removed, localFile)
+	}
+	sort.Strings(removed)
+	for _, localFile := range removed {
+		hash, err := fileContentSHA256(ruleFilePath(cursorDir, localFile))
+		if err == nil && hash != installed[localFile].ContentSHA256 && Prompts.OnLocalChanges != LocalChangesOverwrite {
+			fmt.Printf("Keeping %s, it was removed upstream but has local edits\n", localFile)
+			localFiles = append(localFiles, localFile)
+			hashes[localFile] = installed[localFile].ContentSHA256
+			continue
+		}
+		if err := removeRuleFile(cursorDir, localFile); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s, it no longer exists upstream\n", localFile)
+	}
+
+	oldCommit := rule.ResolvedCommit
+	sort.Strings(localFiles)
+	rule.LocalFiles = localFiles
+	rule.FileSHA256 = hashes
+	rule.MergeConflict = conflict
+	rule.GitRef = "branch=" + branch
+	rule.ResolvedCommit = latestCommit
+
+	fmt.Printf("Updated from %s to %s on branch %s\n", shortCommit(oldCommit), shortCommit(latestCommit), branch)
+	return nil
+}
@@ -0,0 +1,354 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestListGitHubDirFiles tests listing the .mdc files below a directory, from the tree and
+// directory by directory when the tree is truncated.
+func TestListGitHubDirFiles(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		collection := &testCollection{
+			commit: "3333333333333333333333333333333333333333",
+			files: map[string]string{
+				"python/style.mdc":       "style\n",
+				"python/web/django.mdc":  "django\n",
+				"python/README.md":       "readme\n",
+				"python-extra/extra.mdc": "extra\n",
+				"go/style.mdc":           "go\n",
+			},
+			truncated: truncated,
+		}
+		ctx := startTestGitHubServer(t, collection.handler())
+
+		files, err := listGitHubDirFiles(ctx, githubDotCom, "owner", "cursor-rules-collection",
+			collection.commit, "python")
+		if err != nil {
+			t.Fatalf("truncated=%v: listGitHubDirFiles returned error: %v", truncated, err)
+		}
+		expected := []string{"style.mdc", "web/django.mdc"}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("truncated=%v: expected %v, got %v", truncated, expected, files)
+		}
+
+		_, err = listGitHubDirFiles(ctx, githubDotCom, "owner", "cursor-rules-collection",
+			collection.commit, "docs")
+		if err == nil {
+			t.Errorf("truncated=%v: expected an error for a directory without rules, got nil", truncated)
+		}
+	}
+}
+
+// testDirRef is the tree URL the directory rule tests install.
+const testDirRef = "https://github.com/owner/cursor-rules-collection/tree/main/python/"
+
+// installTestGitHubDir serves a collection with a python directory and installs it from
+// testDirRef, returning the installed rule and the context to reach the server with.
+func installTestGitHubDir(t *testing.T, cursorDir string) (context.Context, RuleSource) {
+	t.Helper()
+	collection := &testCollection{
+		commit: "4444444444444444444444444444444444444444",
+		files: map[string]string{
+			"python/style.mdc":      "style v1\n",
+			"python/web/django.mdc": "django\n",
+			"go/style.mdc":          "go\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+
+	if err := AddRuleByReferenceContext(ctx, cursorDir, testDirRef); err != nil {
+		t.Fatalf("AddRuleByReferenceContext returned error: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 1 {
+		t.Fatalf("Expected one rule for the directory, got %d", len(lock.Rules))
+	}
+	return ctx, lock.Rules[0]
+}
+
+// TestAddGitHubDir tests that a tree URL is installed as one rule holding every .mdc file
+// below the directory.
+func TestAddGitHubDir(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	_, rule := installTestGitHubDir(t, cursorDir)
+
+	if rule.Key != "owner/cursor-rules-collection/python" || rule.SourceType != SourceTypeGitHubDir {
+		t.Errorf("Expected github-dir rule owner/cursor-rules-collection/python, got %s rule %s", rule.SourceType, rule.Key)
+	}
+	if rule.GitRef != "branch=main" || rule.ResolvedCommit != "4444444444444444444444444444444444444444" {
+		t.Errorf("Expected branch=main at 4444444, got %s at %s", rule.GitRef, rule.ResolvedCommit)
+	}
+	expectedFiles := []string{"owner/cursor-rules-collection/python/style.mdc", "owner/cursor-rules-collection/python/web/django.mdc"}
+	if !reflect.DeepEqual(rule.LocalFiles, expectedFiles) {
+		t.Errorf("Expected local files %v, got %v", expectedFiles, rule.LocalFiles)
+	}
+
+	report, err := VerifyRules(cursorDir)
+	if err != nil {
+		t.Fatalf("VerifyRules returned error: %v", err)
+	}
+	if len(report.Files) != len(expectedFiles) {
+		t.Errorf("Expected %d verified files, got %+v", len(expectedFiles), report.Files)
+	}
+	for _, f := range report.Files {
+		if f.Status != FileStatusOK {
+			t.Errorf("Expected %s to verify, got %s", f.Path, f.Status)
+		}
+	}
+}
+
+// TestUpgradeGitHubDirRule tests that an upgrade updates changed files, installs files added
+// upstream and deletes files removed upstream.
+func TestUpgradeGitHubDirRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	installTestGitHubDir(t, cursorDir)
+
+	collection := &testCollection{
+		commit: "5555555555555555555555555555555555555555",
+		files: map[string]string{
+			"python/style.mdc":     "style v2\n",
+			"python/web/flask.mdc": "flask\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+
+	if err := UpgradeRuleContext(ctx, cursorDir, "owner/cursor-rules-collection/python"); err != nil {
+		t.Fatalf("UpgradeRuleContext returned error: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	rule := lock.Rules[0]
+	if rule.ResolvedCommit != collection.commit {
+		t.Errorf("Expected resolved commit %s after upgrade, got %s", collection.commit, rule.ResolvedCommit)
+	}
+	expectedFiles := []string{"owner/cursor-rules-collection/python/style.mdc", "owner/cursor-rules-collection/python/web/flask.mdc"}
+	if !reflect.DeepEqual(rule.LocalFiles, expectedFiles) {
+		t.Errorf("Expected local files %v after upgrade, got %v", expectedFiles, rule.LocalFiles)
+	}
+	if fileExists(filepath.Join(cursorDir, "owner", "cursor-rules-collection", "python", "web", "django.mdc")) {
+		t.Error("Expected the file removed upstream to be deleted")
+	}
+	data, err := os.ReadFile(filepath.Join(cursorDir, "owner", "cursor-rules-collection", "python", "style.mdc"))
+	if err != nil || string(data) != "style v2\n" {
+		t.Errorf("Expected upgraded content %q, got %q (%v)", "style v2\n", data, err)
+	}
+}
+
+// TestInstallFrozenGitHubDir tests that a frozen install restores every file of a directory
+// rule and reports the rule once.
+func TestInstallFrozenGitHubDir(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	ctx, rule := installTestGitHubDir(t, cursorDir)
+
+	if err := os.RemoveAll(filepath.Join(cursorDir, "owner")); err != nil {
+		t.Fatalf("Failed to delete rule files: %v", err)
+	}
+	result, err := InstallFrozen(ctx, cursorDir)
+	if err != nil {
+		t.Fatalf("InstallFrozen returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"owner/cursor-rules-collection/python"}) {
+		t.Errorf("Expected the directory to be installed once, got %v", result.Added)
+	}
+	for _, file := range rule.LocalFiles {
+		if !fileExists(filepath.Join(cursorDir, file)) {
+			t.Errorf("Expected %s to be restored", file)
+		}
+	}
+}
+
+// TestRemoveGitHubDirRule tests that removing a directory rule deletes all its files and the
+// directories they leave empty.
+func TestRemoveGitHubDirRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	installTestGitHubDir(t, cursorDir)
+
+	if err := RemoveRule(cursorDir, "owner/cursor-rules-collection/python"); err != nil {
+		t.Fatalf("RemoveRule returned error: %v", err)
+	}
+	if fileExists(filepath.Join(cursorDir, "owner")) {
+		t.Error("Expected the rule's files and directories to be removed")
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 0 {
+		t.Errorf("Expected no rules after removal, got %d", len(lock.Rules))
+	}
+}
+
+// TestUpgradeGitHubDirRuleLocalFiles tests that a file removed upstream but edited locally
+// stays part of the rule, and that a file added upstream doesn't overwrite an untracked
+// file at its path.
+func TestUpgradeGitHubDirRuleLocalFiles(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	_, rule := installTestGitHubDir(t, cursorDir)
+	setPromptPolicy(t, PromptPolicy{OnLocalChanges: LocalChangesKeep}, false)
+
+	djangoFile := "owner/cursor-rules-collection/python/web/django.mdc"
+	flaskFile := "owner/cursor-rules-collection/python/web/flask.mdc"
+	if err := os.WriteFile(ruleFilePath(cursorDir, djangoFile), []byte("my django\n"), 0o644); err != nil {
+		t.Fatalf("Failed to edit rule file: %v", err)
+	}
+	if err := os.WriteFile(ruleFilePath(cursorDir, flaskFile), []byte("my flask\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write untracked file: %v", err)
+	}
+
+	collection := &testCollection{
+		commit: "5555555555555555555555555555555555555555",
+		files: map[string]string{
+			"python/style.mdc":     "style v1\n",
+			"python/web/flask.mdc": "flask\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+	if err := UpgradeRuleContext(ctx, cursorDir, rule.Key); err != nil {
+		t.Fatalf("UpgradeRuleContext returned error: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	upgraded := lock.Rules[0]
+	if upgraded.FileSHA256[djangoFile] != rule.FileSHA256[djangoFile] {
+		t.Errorf("Expected the edited file removed upstream to stay tracked with its last hash, got %v",
+			upgraded.FileSHA256)
+	}
+	if upgraded.FileSHA256[flaskFile] != calculateSHA256([]byte("flask\n")) {
+		t.Errorf("Expected the file added upstream to be tracked, got %v", upgraded.FileSHA256)
+	}
+	for file, expected := range map[string]string{djangoFile: "my django\n", flaskFile: "my flask\n"} {
+		data, err := os.ReadFile(ruleFilePath(cursorDir, file))
+		if err != nil || string(data) != expected {
+			t.Errorf("Expected %s to keep %q, got %q (%v)", file, expected, data, err)
+		}
+	}
+}
+
+// TestUpgradePinnedGitHubDirRule tests that unpinning a directory rule follows the
+// repository's default branch.
+func TestUpgradePinnedGitHubDirRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	_, rule := installTestGitHubDir(t, cursorDir)
+	setPromptPolicy(t, PromptPolicy{Unpin: true}, false)
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	lock.Rules[0].GitRef = "commit=" + rule.ResolvedCommit
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	collection := &testCollection{
+		commit:        "6666666666666666666666666666666666666666",
+		files:         map[string]string{"python/style.mdc": "style v1\n", "python/web/django.mdc": "django\n"},
+		defaultBranch: "trunk",
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+	if err := UpgradeRuleContext(ctx, cursorDir, rule.Key); err != nil {
+		t.Fatalf("UpgradeRuleContext returned error: %v", err)
+	}
+
+	lock, err = LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if lock.Rules[0].GitRef != "branch=trunk" || lock.Rules[0].ResolvedCommit != collection.commit {
+		t.Errorf("Expected branch=trunk at %s, got %s at %s", collection.commit, lock.Rules[0].GitRef,
+			lock.Rules[0].ResolvedCommit)
+	}
+}
+
+// TestAddGitHubDirKeyConflict tests that a directory whose key another rule has is not
+// installed over that rule's files.
+func TestAddGitHubDirKeyConflict(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	key := generateRuleKey(testDirRef)
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:        key,
+		SourceType: SourceTypeLocalAbs,
+		Reference:  "/elsewhere/python.mdc",
+		LocalFiles: []string{key + ".mdc"},
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	collection := &testCollection{
+		commit: "4444444444444444444444444444444444444444",
+		files:  map[string]string{"python/style.mdc": "style v1\n"},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+	err := AddRuleByReferenceContext(ctx, cursorDir, testDirRef)
+	if !IsRuleKeyConflictError(err) {
+		t.Errorf("Expected ErrRuleKeyConflict, got %v", err)
+	}
+	if fileExists(ruleFilePath(cursorDir, key+"/style.mdc")) {
+		t.Error("Expected no files to be written for a conflicting key")
+	}
+}
+
+// TestOutdatedGitHubDirRule tests that a directory rule is only outdated when its files
+// changed, not whenever its branch moves, matching status --upstream.
+func TestOutdatedGitHubDirRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	_, rule := installTestGitHubDir(t, cursorDir)
+
+	// Only a file outside the directory changed
+	collection := &testCollection{
+		commit: "5555555555555555555555555555555555555555",
+		files: map[string]string{
+			"python/style.mdc":      "style v1\n",
+			"python/web/django.mdc": "django\n",
+			"go/style.mdc":          "go v2\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+
+	row, err := checkRuleOutdated(ctx, cursorDir, rule)
+	if err != nil || row != nil {
+		t.Errorf("Expected the directory not to be outdated, got %+v (%v)", row, err)
+	}
+	changed, err := checkUpstreamDirChanged(ctx, rule)
+	if err != nil || changed {
+		t.Errorf("Expected status --upstream to agree, got %v (%v)", changed, err)
+	}
+
+	// A file inside the directory changed
+	collection = &testCollection{
+		commit: "6666666666666666666666666666666666666666",
+		files: map[string]string{
+			"python/style.mdc":      "style v2\n",
+			"python/web/django.mdc": "django\n",
+		},
+	}
+	ctx = startTestGitHubServer(t, collection.handler())
+
+	row, err = checkRuleOutdated(ctx, cursorDir, rule)
+	if err != nil || row == nil {
+		t.Fatalf("Expected the directory to be outdated, got %+v (%v)", row, err)
+	}
+	if row.Latest != "main@6666666" {
+		t.Errorf("Expected latest main@6666666, got %s", row.Latest)
+	}
+	changed, err = checkUpstreamDirChanged(ctx, rule)
+	if err != nil || !changed {
+		t.Errorf("Expected status --upstream to agree, got %v (%v)", changed, err)
+	}
+}
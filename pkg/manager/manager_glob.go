@@ -46,6 +46,9 @@ func handleGlobPattern(ctx context.Context, cursorDir, ref string) error {
 func handleLocalGlobPattern(ctx context.Context, cursorDir, pattern string, g glob.Glob) error {
 	Debugf("Processing local glob pattern: %s\n", pattern)
 
+	// Keep the pattern as written so installed rules can be traced back to it
+	globRef := pattern
+
 	// Load lockfile once at the beginning
 	lock, err := LoadLockFile(cursorDir)
 	if err != nil {
@@ -125,6 +128,9 @@ func handleLocalGlobPattern(ctx context.Context, cursorDir, pattern string, g gl
 			continue
 		}
 
+		// Store the original glob pattern
+		rule.GlobPattern = globRef
+
 		// Check if rule is already installed
 		if lock.IsInstalled(rule.Key) {
 			// Find the existing rule
@@ -172,11 +178,6 @@ func handleLocalGlobPattern(ctx context.Context, cursorDir, pattern string, g gl
 	if len(newRules) > 0 {
 		lock.Rules = append(lock.Rules, newRules...)
 
-		// For backwards compatibility
-		for _, rule := range newRules {
-			lock.Installed = append(lock.Installed, rule.Key)
-		}
-
 		err = lock.Save(cursorDir)
 		if err != nil {
 			return fmt.Errorf("failed to update lockfile: %w", err)
@@ -204,8 +205,8 @@ func handleUsernameGlobPattern(ctx context.Context, cursorDir, username, pattern
 	repo := "cursor-rules-collection"
 	branch := "main" // Default to main branch
 
-	// Get list of files from GitHub
-	files, err := listGitHubRepoFiles(ctx, owner, repo, branch, pattern)
+	// Get list of files from GitHub, all at the same commit of the branch
+	commit, files, err := listGitHubRepoFiles(ctx, shorthandGitHubHost(), owner, repo, branch, pattern)
 	if err != nil {
 		// If we can't list, tell the user and suggest alternatives
 		fmt.Printf("Could not list files matching pattern: %v\n", err)
@@ -213,72 +214,87 @@ func handleUsernameGlobPattern(ctx context.Context, cursorDir, username, pattern
 		return fmt.Errorf("failed to list files matching pattern: %w", err)
 	}
 
-	// Track success/failure
-	successCount := 0
-	errorCount := 0
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
 
-	// Process each matching file
+	// Collect the matching .mdc files that aren't installed yet
+	var fileRefs, githubURLs []string
+	skippedCount := 0
 	for _, file := range files {
-		// Check if it matches our pattern
-		if !matchGlob(g, file) {
+		if !matchGlob(g, file) || !strings.HasSuffix(file, ".mdc") {
 			continue
 		}
 
-		// Only process .mdc files
-		if !strings.HasSuffix(file, ".mdc") {
+		// Directly construct GitHub URL to avoid recursive call to AddRuleByReference
+		githubURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s",
+			username, file))
+		if key := generateRuleKey(githubURL); lock.IsInstalled(key) {
+			fmt.Printf("Rule already installed: %s\n", key)
+			skippedCount++
 			continue
 		}
 
-		// Construct a new reference without the glob
-		fileRef := fmt.Sprintf("%s/%s", username, file)
-
-		// Directly construct GitHub URL to avoid recursive call to AddRuleByReference
-		githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s",
-			username, file)
+		fileRefs = append(fileRefs, fmt.Sprintf("%s/%s", username, file))
+		githubURLs = append(githubURLs, githubURL)
+	}
 
-		rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
+	// Download in parallel; the rules stay in listing order
+	rules := make([]RuleSource, len(fileRefs))
+	errs := make([]error, len(fileRefs))
+	progress := newProgressPrinter(len(fileRefs))
+	forEachParallel(len(fileRefs), func(i int) {
+		ctx, warnings := withItemWarnings(ctx)
+		rule, err := handleGitHubBlobAtCommit(ctx, cursorDir, githubURLs[i], commit)
 		if err != nil {
-			fmt.Printf("Warning: Could not add rule %s: %v\n", fileRef, err)
-			errorCount++
-		} else {
-			// Found in the cursor-rules-collection repo
-			rule.SourceType = SourceTypeGitHubShorthand
-			rule.Reference = fileRef // Store the original reference
-
-			// Update lockfile with the new rule
-			lock, err := LoadLockFile(cursorDir)
-			if err != nil {
-				fmt.Printf("Warning: Could not load lockfile for %s: %v\n", fileRef, err)
-				errorCount++
-				continue
-			}
-
-			if lock.IsInstalled(rule.Key) {
-				fmt.Printf("Rule already installed: %s\n", rule.Key)
-				continue
-			}
+			errs[i] = err
+			progress.printItemf(warnings, "failed %s: %v", fileRefs[i], err)
+			return
+		}
 
-			lock.Rules = append(lock.Rules, rule)
-			// For backwards compatibility
-			lock.Installed = append(lock.Installed, rule.Key)
+		// Found in the cursor-rules-collection repo
+		rule.SourceType = SourceTypeGitHubShorthand
+		rule.Reference = fileRefs[i] // Store the original reference
+		rule.GlobPattern = username + "/" + pattern
+		rules[i] = rule
+		progress.printItemf(warnings, "downloaded %s", fileRefs[i])
+	})
 
-			err = lock.Save(cursorDir)
-			if err != nil {
-				fmt.Printf("Warning: Could not update lockfile for %s: %v\n", fileRef, err)
-				errorCount++
-				continue
+	// Record all downloaded rules with a single lockfile write
+	successCount := 0
+	errorCount := 0
+	var rateLimitErr error
+	for i := range fileRefs {
+		if errs[i] != nil {
+			errorCount++
+			if rateLimitErr == nil && IsGitHubRateLimitError(errs[i]) {
+				rateLimitErr = errs[i]
 			}
+			continue
+		}
+		lock.Rules = append(lock.Rules, rules[i])
+		successCount++
+	}
 
-			successCount++
+	if successCount > 0 {
+		if err := lock.Save(cursorDir); err != nil {
+			return fmt.Errorf("failed to update lockfile: %w", err)
 		}
 	}
 
+	if rateLimitErr != nil {
+		// The rules downloaded before the limit was hit are in the lockfile
+		return fmt.Errorf("stopped after adding %d rule(s): %w", successCount, rateLimitErr)
+	}
+
 	// Report results
-	if successCount == 0 {
+	if successCount == 0 && skippedCount == 0 {
 		return fmt.Errorf("no matching rules found for pattern: %s", pattern)
 	}
 
-	fmt.Printf("Added %d rules matching pattern %s (errors: %d)\n", successCount, pattern, errorCount)
+	fmt.Printf("Added %d rules matching pattern %s (skipped: %d, errors: %d)\n",
+		successCount, pattern, skippedCount, errorCount)
 	return nil
 }
 
@@ -306,7 +322,7 @@ func handleTemplateGlobPattern(cursorDir, pattern string, g glob.Glob) error {
 		targetPath := filepath.Join(cursorDir, tmpl.Name+".mdc")
 
 		// Write to .cursor/rules/{ruleName}.mdc
-		err := os.WriteFile(targetPath, []byte(content), 0o644)
+		err := writeRuleFile(targetPath, []byte(content), 0o644)
 		if err != nil {
 			fmt.Printf("Warning: Could not write template %s: %v\n", tmpl.Name, err)
 			errorCount++
@@ -331,12 +347,12 @@ func handleTemplateGlobPattern(cursorDir, pattern string, g glob.Glob) error {
 			SourceType: SourceTypeBuiltIn,
 			Reference:  tmpl.Name,
 			Category:   tmpl.Category,
-			LocalFiles: []string{targetPath},
+			LocalFiles: []string{tmpl.Name + ".mdc"},
+			// Calculate and store content hash for future modification checks
+			ContentSHA256: calculateSHA256([]byte(content)),
 		}
 
 		lock.Rules = append(lock.Rules, rule)
-		// For backwards compatibility
-		lock.Installed = append(lock.Installed, tmpl.Name)
 
 		err = lock.Save(cursorDir)
 		if err != nil {
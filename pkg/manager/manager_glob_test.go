@@ -0,0 +1,195 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testCollection is a fake username/cursor-rules-collection repository served through the
+// GitHub API and raw content endpoints of startTestGitHubServer.
+type testCollection struct {
+	commit    string
+	files     map[string]string
+	truncated bool
+
+	// Default branch of the repository, "main" when empty
+	defaultBranch string
+
+	// Requests made to the tree and contents endpoints
+	treeRequests     int
+	contentsRequests []string
+}
+
+// handler serves the repository, branch, tree, contents and raw endpoints of the collection.
+func (c *testCollection) handler() http.Handler {
+	const repo = "/repos/owner/cursor-rules-collection"
+	branch := c.defaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(repo, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"default_branch": branch})
+	})
+	mux.HandleFunc(repo+"/branches/"+branch, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"commit": map[string]string{"sha": c.commit}})
+	})
+	mux.HandleFunc(repo+"/git/trees/"+c.commit, func(w http.ResponseWriter, r *http.Request) {
+		c.treeRequests++
+		var tree []gitTreeEntry
+		for path := range c.files {
+			tree = append(tree, gitTreeEntry{Path: path, Type: "blob"})
+			if dir := filepath.Dir(path); dir != "." {
+				tree = append(tree, gitTreeEntry{Path: dir, Type: "tree"})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": "tree-sha", "tree": tree, "truncated": c.truncated})
+	})
+	mux.HandleFunc(repo+"/contents/", func(w http.ResponseWriter, r *http.Request) {
+		c.contentsRequests = append(c.contentsRequests, r.URL.RequestURI())
+		dir := strings.TrimPrefix(r.URL.Path, repo+"/contents/")
+
+		type item struct {
+			Name string `json:"name"`
+			Path string `json:"path"`
+			Type string `json:"type"`
+		}
+		var items []item
+		seen := map[string]bool{}
+		for path := range c.files {
+			rest, ok := strings.CutPrefix(path, dir)
+			if dir != "" && !ok {
+				continue
+			}
+			rest = strings.TrimPrefix(rest, "/")
+			name, _, isDir := strings.Cut(rest, "/")
+			if isDir {
+				if !seen[name] {
+					seen[name] = true
+					items = append(items, item{Name: name, Path: strings.TrimPrefix(dir+"/"+name, "/"), Type: "dir"})
+				}
+				continue
+			}
+			items = append(items, item{Name: name, Path: path, Type: "file"})
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	})
+	mux.HandleFunc("/owner/cursor-rules-collection/"+c.commit+"/", func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/owner/cursor-rules-collection/"+c.commit+"/")
+		content, ok := c.files[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(content))
+	})
+	return mux
+}
+
+// TestListGitHubRepoFiles tests listing matching files with one tree request, and the
+// per-directory fallback for truncated trees.
+func TestListGitHubRepoFiles(t *testing.T) {
+	collection := &testCollection{
+		commit: "4444444444444444444444444444444444444444",
+		files: map[string]string{
+			"root.mdc":            "root\n",
+			"README.md":           "readme\n",
+			"frontend/react.mdc":  "react\n",
+			"frontend/vue.mdc":    "vue\n",
+			"backend/go/http.mdc": "http\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.mdc", []string{"root.mdc"}},
+		{"frontend/*", []string{"frontend/react.mdc", "frontend/vue.mdc"}},
+		{"**/*.mdc", []string{"backend/go/http.mdc", "frontend/react.mdc", "frontend/vue.mdc"}},
+	}
+
+	for _, truncated := range []bool{false, true} {
+		collection.truncated = truncated
+		for _, tt := range tests {
+			collection.treeRequests, collection.contentsRequests = 0, nil
+
+			commit, files, err := listGitHubRepoFiles(ctx, githubDotCom, "owner", "cursor-rules-collection", "main", tt.pattern)
+			if err != nil {
+				t.Fatalf("listGitHubRepoFiles(%s) returned error: %v", tt.pattern, err)
+			}
+			if commit != collection.commit {
+				t.Errorf("Expected commit %s, got %s", collection.commit, commit)
+			}
+
+			sorted := append([]string{}, files...)
+			sort.Strings(sorted)
+			if !reflect.DeepEqual(sorted, tt.expected) {
+				t.Errorf("truncated=%v, %s: expected %v, got %v", truncated, tt.pattern, tt.expected, sorted)
+			}
+
+			if collection.treeRequests != 1 {
+				t.Errorf("Expected 1 tree request, got %d", collection.treeRequests)
+			}
+			if !truncated && len(collection.contentsRequests) > 0 {
+				t.Errorf("Expected no contents requests for a complete tree, got %v", collection.contentsRequests)
+			}
+			for _, uri := range collection.contentsRequests {
+				if !strings.HasSuffix(uri, "?ref="+collection.commit) {
+					t.Errorf("Contents request %s is not pinned to the listed commit", uri)
+				}
+			}
+		}
+	}
+}
+
+// TestHandleUsernameGlobPattern tests that every matched rule is installed from the commit
+// the tree was listed at.
+func TestHandleUsernameGlobPattern(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	collection := &testCollection{
+		commit: "5555555555555555555555555555555555555555",
+		files: map[string]string{
+			"frontend/react.mdc": "react\n",
+			"frontend/vue.mdc":   "vue\n",
+			"backend/go.mdc":     "go\n",
+		},
+	}
+	ctx := startTestGitHubServer(t, collection.handler())
+
+	g, err := compileGlob("frontend/*.mdc")
+	if err != nil {
+		t.Fatalf("compileGlob returned error: %v", err)
+	}
+	if err := handleUsernameGlobPattern(ctx, cursorDir, "owner", "frontend/*.mdc", g); err != nil {
+		t.Fatalf("handleUsernameGlobPattern returned error: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(lock.Rules))
+	}
+	for _, rule := range lock.Rules {
+		if rule.ResolvedCommit != collection.commit {
+			t.Errorf("Rule %s: expected resolved commit %s, got %s", rule.Key, collection.commit, rule.ResolvedCommit)
+		}
+		if rule.GitRef != "branch=main" {
+			t.Errorf("Rule %s: expected to keep tracking branch=main, got %s", rule.Key, rule.GitRef)
+		}
+		if rule.GlobPattern != "owner/frontend/*.mdc" {
+			t.Errorf("Rule %s: expected glob pattern owner/frontend/*.mdc, got %s", rule.Key, rule.GlobPattern)
+		}
+	}
+}
@@ -16,6 +16,52 @@ func updateLockfileWithRule(cursorDir string, rule RuleSource) error {
 		return fmt.Errorf("failed to load lockfile: %w", err)
 	}
 
+	if !addRuleToLock(lock, rule) {
+		return nil
+	}
+
+	err = lock.Save(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to update lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// checkRuleKeyAvailable returns an error if the lockfile has a rule with the key that was
+// installed from another file or directory than sourceURL, a GitHub blob or tree URL. The
+// same file at another ref is left to addRuleToLock. Handlers call it before writing
+// anything, so a reference whose key collides can't overwrite another rule's files.
+func checkRuleKeyAvailable(cursorDir, key, sourceURL string) error {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	for _, rule := range lock.Rules {
+		if rule.Key != key {
+			continue
+		}
+
+		if host, owner, repo, path, err := githubRuleLocation(rule); err == nil {
+			parse := parseGitHubBlobURL
+			if rule.SourceType == SourceTypeGitHubDir {
+				parse = parseGitHubTreeURL
+			}
+			newHost, newOwner, newRepo, _, newPath, ok := parse(sourceURL)
+			if ok && newHost.WebURL == host.WebURL && newOwner == owner && newRepo == repo && newPath == path {
+				return nil
+			}
+		}
+		return &ErrRuleKeyConflict{RuleKey: key, Existing: rule.Reference}
+	}
+	return nil
+}
+
+// addRuleToLock appends a rule to the lockfile unless a rule with its key is already
+// installed, in which case it explains how the two differ. It reports whether the rule
+// was added.
+func addRuleToLock(lock *LockFile, rule RuleSource) bool {
 	// Check if rule is already installed and handle conflicts/updates
 	if lock.IsInstalled(rule.Key) {
 		// Find the existing rule
@@ -34,7 +80,7 @@ func updateLockfileWithRule(cursorDir string, rule RuleSource) error {
 			fmt.Printf("  Current commit: %s\n", existingRule.ResolvedCommit)
 			fmt.Printf("  New commit: %s\n", rule.ResolvedCommit)
 			fmt.Printf("To update, use: cursor-rules upgrade %s\n", rule.Key)
-			return nil
+			return false
 		}
 
 		// If content hash is available, compare that
@@ -42,25 +88,17 @@ func updateLockfileWithRule(cursorDir string, rule RuleSource) error {
 			rule.ContentSHA256 != existingRule.ContentSHA256 {
 			fmt.Printf("Rule '%s' is already installed but has different content.\n", rule.Key)
 			fmt.Printf("To update, use: cursor-rules upgrade %s\n", rule.Key)
-			return nil
+			return false
 		}
 
 		// If we get here, the rule is the same or we can't determine differences
 		fmt.Printf("Rule '%s' is already installed and up-to-date.\n", rule.Key)
-		return nil
+		return false
 	}
 
 	// Update lockfile with the new rule
 	lock.Rules = append(lock.Rules, rule)
-	// For backwards compatibility
-	lock.Installed = append(lock.Installed, rule.Key)
-
-	err = lock.Save(cursorDir)
-	if err != nil {
-		return fmt.Errorf("failed to update lockfile: %w", err)
-	}
-
-	return nil
+	return true
 }
 
 // GlobPatternHandler handles glob pattern references
@@ -100,7 +138,7 @@ func (h *GitHubTreeHandler) CanHandle(ref string) bool {
 }
 
 func (h *GitHubTreeHandler) Process(ctx context.Context, cursorDir, ref string) (RuleSource, error) {
-	return handleGitHubDir(cursorDir, ref)
+	return handleGitHubDir(ctx, cursorDir, ref)
 }
 
 // AbsolutePathHandler handles absolute path references
@@ -216,24 +254,19 @@ func (h *DefaultUsernameHandler) Process(ctx context.Context, cursorDir, ref str
 	defaultRef := defaultUsername + "/" + ref
 
 	// Directly construct GitHub URL
-	githubURL := fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s.mdc",
-		defaultUsername, ref)
-
-	rule, err := handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo
-		rule.SourceType = SourceTypeGitHubShorthand
-		rule.Reference = defaultRef // Store the resolved reference
-		return rule, nil
-	}
+	githubURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s.mdc",
+		defaultUsername, ref))
 
 	// If not found, try with potential paths (could be nested)
-	githubURL = fmt.Sprintf("https://github.com/%s/cursor-rules-collection/blob/main/%s/%s.mdc",
-		defaultUsername, ref, ref)
+	subdirURL := shorthandBlobURL(fmt.Sprintf("%s/cursor-rules-collection/blob/main/%s/%s.mdc",
+		defaultUsername, ref, ref))
 
-	rule, err = handleGitHubBlob(ctx, cursorDir, githubURL)
-	if err == nil {
-		// Found in the cursor-rules-collection repo in a subdirectory
+	rule, found, err := installFirstGitHubBlob(ctx, cursorDir, handleGitHubBlob, githubURL, subdirURL)
+	if err != nil {
+		return RuleSource{}, err
+	}
+	if found >= 0 {
+		// Found in the cursor-rules-collection repo
 		rule.SourceType = SourceTypeGitHubShorthand
 		rule.Reference = defaultRef // Store the resolved reference
 		return rule, nil
@@ -250,5 +283,5 @@ func (h *DefaultUsernameHandler) Process(ctx context.Context, cursorDir, ref str
 		}
 	}
 
-	return RuleSource{}, fmt.Errorf("rule not found with default username: %s", ref)
+	return RuleSource{}, ruleNotFoundError(ref, "rule not found with default username: %s", ref)
 }
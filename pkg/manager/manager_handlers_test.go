@@ -1,6 +1,9 @@
 package manager
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -203,3 +206,44 @@ func TestDefaultUsernameHandler(t *testing.T) {
 		}
 	})
 }
+
+// TestCheckRuleKeyAvailable tests that a reference whose key is taken by a rule from another
+// source fails before anything is written.
+func TestCheckRuleKeyAvailable(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	localFile := filepath.Join(cursorDir, "owner", "repo", "rule.mdc")
+	if err := os.MkdirAll(filepath.Dir(localFile), 0o755); err != nil {
+		t.Fatalf("Failed to create rule directory: %v", err)
+	}
+	if err := os.WriteFile(localFile, []byte("local rule\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write rule: %v", err)
+	}
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:        "owner/repo/rule",
+		SourceType: SourceTypeLocalAbs,
+		Reference:  "/elsewhere/owner/repo/rule.mdc",
+		LocalFiles: []string{"owner/repo/rule.mdc"},
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	_, err := handleGitHubBlob(context.Background(), cursorDir, "https://github.com/owner/repo/blob/main/rule.mdc")
+	if !IsRuleKeyConflictError(err) {
+		t.Fatalf("Expected ErrRuleKeyConflict for a key used by another rule, got %v", err)
+	}
+	if data, _ := os.ReadFile(localFile); string(data) != "local rule\n" {
+		t.Errorf("Expected the existing rule file to be untouched, got %q", data)
+	}
+
+	// The same file at another ref is the same rule
+	lock.Rules[0].SourceType = SourceTypeGitHubFile
+	lock.Rules[0].Reference = "https://github.com/owner/repo/blob/v1.0.0/rule.mdc"
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+	if err := checkRuleKeyAvailable(cursorDir, "owner/repo/rule", "https://github.com/owner/repo/blob/main/rule.mdc"); err != nil {
+		t.Errorf("Expected the same file at another ref to be accepted, got %v", err)
+	}
+}
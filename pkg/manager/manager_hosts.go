@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// GitHubHost is a GitHub instance rules can be installed from: github.com or a GitHub
+// Enterprise server.
+type GitHubHost struct {
+	// Base URL of the web interface, as it appears in blob and tree URLs
+	WebURL string `json:"webURL"`
+
+	// Base URL of the REST API (GitHub Enterprise default: <webURL>/api/v3)
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Base URL raw file content is served from (GitHub Enterprise default: <webURL>/raw)
+	RawURL string `json:"rawURL,omitempty"`
+
+	// Token for this host. github.com uses GITHUB_TOKEN and the other sources instead
+	Token string `json:"token,omitempty"`
+
+	// Resolve username/rule shorthand references on this host instead of github.com
+	Default bool `json:"default,omitempty"`
+
+	// Send the token over plain http. Without it, a host with an http URL can't have a token
+	AllowInsecure bool `json:"allowInsecure,omitempty"`
+}
+
+// githubDotCom is the public GitHub. Tests point its API and raw URLs at a local server.
+var githubDotCom = &GitHubHost{
+	WebURL: "https://github.com",
+	APIURL: "https://api.github.com",
+	RawURL: "https://raw.githubusercontent.com",
+}
+
+// GitHubHosts are the GitHub Enterprise hosts configured in the user config, see LoadGitHubHosts.
+var GitHubHosts []*GitHubHost
+
+// LoadGitHubHosts reads githubHosts from the user config into GitHubHosts, filling in the
+// default API and raw URLs of GitHub Enterprise.
+func LoadGitHubHosts() error {
+	config, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+
+	hosts := make([]*GitHubHost, 0, len(config.GitHubHosts))
+	defaults := 0
+	for i := range config.GitHubHosts {
+		host := config.GitHubHosts[i]
+		if err := host.normalize(); err != nil {
+			return fmt.Errorf("invalid githubHosts entry %d: %w", i+1, err)
+		}
+		if host.Default {
+			defaults++
+		}
+		if host.Token == "" {
+			// Like github.com, fall back to the gh CLI config and ~/.netrc for this host
+			var source string
+			if host.Token, source = lookupCredentialFiles(host.credentialHosts()); host.Token != "" {
+				Debugf("Using token for %s from %s\n", host.WebURL, source)
+			}
+		}
+		if host.Token != "" && !host.isHTTPS() {
+			if !host.AllowInsecure {
+				return fmt.Errorf("invalid githubHosts entry %d: a token for %s would be sent over http; use https URLs or set allowInsecure", i+1, host.WebURL)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: the token for %s is sent unencrypted over http\n", host.WebURL)
+		}
+		registerSecret(host.Token)
+		hosts = append(hosts, &host)
+	}
+	if defaults > 1 {
+		return fmt.Errorf("invalid githubHosts: only one host can be the default")
+	}
+
+	GitHubHosts = hosts
+	return nil
+}
+
+// normalize validates the URLs of a configured host and fills in the defaults.
+func (h *GitHubHost) normalize() error {
+	h.WebURL = strings.TrimSuffix(h.WebURL, "/")
+	if h.WebURL == "" {
+		return fmt.Errorf("webURL is required")
+	}
+	if h.APIURL == "" {
+		h.APIURL = h.WebURL + "/api/v3"
+	}
+	if h.RawURL == "" {
+		h.RawURL = h.WebURL + "/raw"
+	}
+	h.APIURL = strings.TrimSuffix(h.APIURL, "/")
+	h.RawURL = strings.TrimSuffix(h.RawURL, "/")
+
+	for _, base := range []string{h.WebURL, h.APIURL, h.RawURL} {
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%q is not an http(s) URL", base)
+		}
+	}
+	return nil
+}
+
+// isHTTPS reports whether every request to the host is encrypted.
+func (h *GitHubHost) isHTTPS() bool {
+	for _, base := range []string{h.APIURL, h.RawURL} {
+		if u, err := url.Parse(base); err != nil || u.Scheme != "https" {
+			return false
+		}
+	}
+	return true
+}
+
+// credentialHosts returns the host names a token in the gh CLI config or ~/.netrc may be
+// stored under for this host: those of its web and API URLs.
+func (h *GitHubHost) credentialHosts() []string {
+	var names []string
+	for _, base := range []string{h.WebURL, h.APIURL} {
+		if u, err := url.Parse(base); err == nil && u.Hostname() != "" && !slices.Contains(names, u.Hostname()) {
+			names = append(names, u.Hostname())
+		}
+	}
+	return names
+}
+
+// allGitHubHosts returns the configured hosts followed by github.com.
+func allGitHubHosts() []*GitHubHost {
+	return append(append([]*GitHubHost{}, GitHubHosts...), githubDotCom)
+}
+
+// shorthandGitHubHost returns the host username/rule references are resolved on.
+func shorthandGitHubHost() *GitHubHost {
+	for _, host := range GitHubHosts {
+		if host.Default {
+			return host
+		}
+	}
+	return githubDotCom
+}
+
+// shorthandBlobURL returns the web URL of a path ("owner/repo/blob/ref/file") on the
+// shorthand host.
+func shorthandBlobURL(path string) string {
+	return shorthandGitHubHost().WebURL + "/" + path
+}
+
+// findGitHubWebHost returns the host whose web interface serves a URL, or nil.
+func findGitHubWebHost(ref string) *GitHubHost {
+	for _, host := range allGitHubHosts() {
+		if strings.HasPrefix(ref, host.WebURL+"/") {
+			return host
+		}
+	}
+	return nil
+}
+
+// findGitHubHostForRequest returns the host whose API or raw URL serves requestURL: the
+// scheme, host name and port must all match.
+func findGitHubHostForRequest(requestURL *url.URL) *GitHubHost {
+	for _, host := range allGitHubHosts() {
+		for _, base := range []string{host.APIURL, host.RawURL} {
+			if baseURL, err := url.Parse(base); err == nil && sameOrigin(baseURL, requestURL) {
+				return host
+			}
+		}
+	}
+	return nil
+}
+
+// sameOrigin reports whether two URLs have the same scheme, host name and port, where a
+// missing port is the scheme's default.
+func sameOrigin(a, b *url.URL) bool {
+	port := func(u *url.URL) string {
+		if p := u.Port(); p != "" {
+			return p
+		}
+		if strings.EqualFold(u.Scheme, "http") {
+			return "80"
+		}
+		return "443"
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Hostname(), b.Hostname()) && port(a) == port(b)
+}
+
+// parseGitHubURL splits a web URL like "<webURL>/owner/repo/<kind>/ref/path" of any known
+// host, where kind is "blob" or "tree".
+func parseGitHubURL(ref, kind string) (host *GitHubHost, owner, repo, gitRef, path string, ok bool) {
+	for _, h := range allGitHubHosts() {
+		rest, found := strings.CutPrefix(ref, h.WebURL+"/")
+		if !found {
+			continue
+		}
+
+		parts := strings.SplitN(rest, "/", 5)
+		if len(parts) != 5 || parts[2] != kind {
+			return nil, "", "", "", "", false
+		}
+		for _, part := range parts {
+			if part == "" {
+				return nil, "", "", "", "", false
+			}
+		}
+		return h, parts[0], parts[1], parts[3], parts[4], true
+	}
+	return nil, "", "", "", "", false
+}
@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setGitHubHosts configures hosts for the duration of a test.
+func setGitHubHosts(t *testing.T, hosts ...*GitHubHost) {
+	t.Helper()
+	old := GitHubHosts
+	GitHubHosts = hosts
+	t.Cleanup(func() {
+		GitHubHosts = old
+	})
+}
+
+// TestLoadGitHubHosts tests reading githubHosts from the user config.
+func TestLoadGitHubHosts(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	configPath := filepath.Join(tempDir, "config.json")
+	t.Setenv(UserConfigPathEnv, configPath)
+	setGitHubHosts(t)
+
+	tests := []struct {
+		name        string
+		config      string
+		expectError bool
+		expected    []GitHubHost
+	}{
+		{
+			name:     "no hosts",
+			config:   `{}`,
+			expected: []GitHubHost{},
+		},
+		{
+			name:   "default API and raw URLs",
+			config: `{"githubHosts": [{"webURL": "https://github.example.com/", "default": true}]}`,
+			expected: []GitHubHost{{
+				WebURL:  "https://github.example.com",
+				APIURL:  "https://github.example.com/api/v3",
+				RawURL:  "https://github.example.com/raw",
+				Default: true,
+			}},
+		},
+		{
+			name:   "explicit API and raw URLs",
+			config: `{"githubHosts": [{"webURL": "https://ghe.example.com", "apiURL": "https://api.ghe.example.com/", "rawURL": "https://raw.ghe.example.com"}]}`,
+			expected: []GitHubHost{{
+				WebURL: "https://ghe.example.com",
+				APIURL: "https://api.ghe.example.com",
+				RawURL: "https://raw.ghe.example.com",
+			}},
+		},
+		{
+			name:        "missing webURL",
+			config:      `{"githubHosts": [{"apiURL": "https://ghe.example.com/api/v3"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "not an http URL",
+			config:      `{"githubHosts": [{"webURL": "ghe.example.com"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "token over http",
+			config:      `{"githubHosts": [{"webURL": "http://ghe.example.com", "token": "secret"}]}`,
+			expectError: true,
+		},
+		{
+			name:   "token over http with allowInsecure",
+			config: `{"githubHosts": [{"webURL": "http://ghe.example.com", "token": "secret", "allowInsecure": true}]}`,
+			expected: []GitHubHost{{
+				WebURL:        "http://ghe.example.com",
+				APIURL:        "http://ghe.example.com/api/v3",
+				RawURL:        "http://ghe.example.com/raw",
+				Token:         "secret",
+				AllowInsecure: true,
+			}},
+		},
+		{
+			name:   "http without a token",
+			config: `{"githubHosts": [{"webURL": "http://ghe.example.com"}]}`,
+			expected: []GitHubHost{{
+				WebURL: "http://ghe.example.com",
+				APIURL: "http://ghe.example.com/api/v3",
+				RawURL: "http://ghe.example.com/raw",
+			}},
+		},
+		{
+			name:        "two defaults",
+			config:      `{"githubHosts": [{"webURL": "https://a.example.com", "default": true}, {"webURL": "https://b.example.com", "default": true}]}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.config), 0o600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			err := LoadGitHubHosts()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadGitHubHosts returned error: %v", err)
+			}
+
+			if len(GitHubHosts) != len(tt.expected) {
+				t.Fatalf("Expected %d hosts, got %d", len(tt.expected), len(GitHubHosts))
+			}
+			for i, host := range GitHubHosts {
+				if *host != tt.expected[i] {
+					t.Errorf("Expected host %+v, got %+v", tt.expected[i], *host)
+				}
+			}
+		})
+	}
+}
+
+// TestParseGitHubURL tests splitting blob and tree URLs of github.com and configured hosts.
+func TestParseGitHubURL(t *testing.T) {
+	ghe := &GitHubHost{
+		WebURL: "https://github.example.com",
+		APIURL: "https://github.example.com/api/v3",
+		RawURL: "https://github.example.com/raw",
+	}
+	setGitHubHosts(t, ghe)
+
+	tests := []struct {
+		ref          string
+		kind         string
+		expectOK     bool
+		expectedHost *GitHubHost
+		expectedPath string
+	}{
+		{"https://github.com/owner/repo/blob/main/rules/rule.mdc", "blob", true, githubDotCom, "rules/rule.mdc"},
+		{"https://github.example.com/owner/repo/blob/v1.0.0/rule.mdc", "blob", true, ghe, "rule.mdc"},
+		{"https://github.example.com/owner/repo/tree/main/rules", "tree", true, ghe, "rules"},
+		{"https://github.example.com/owner/repo/tree/main/rules", "blob", false, nil, ""},
+		{"https://github.com/owner/repo/blob/main", "blob", false, nil, ""},
+		{"https://gitlab.com/owner/repo/blob/main/rule.mdc", "blob", false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		host, owner, repo, _, path, ok := parseGitHubURL(tt.ref, tt.kind)
+		if ok != tt.expectOK {
+			t.Errorf("%s (%s): expected ok=%v, got %v", tt.ref, tt.kind, tt.expectOK, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if host != tt.expectedHost || owner != "owner" || repo != "repo" || path != tt.expectedPath {
+			t.Errorf("%s: got host %s, %s/%s, path %q", tt.ref, host.WebURL, owner, repo, path)
+		}
+	}
+}
+
+// TestGitHubEnterpriseHost tests installing from a GitHub Enterprise host with its own token,
+// both from a blob URL and from a shorthand reference resolved on the default host.
+func TestGitHubEnterpriseHost(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	const (
+		token  = "ghe-token"
+		commit = "3333333333333333333333333333333333333333"
+	)
+
+	var unauthenticated []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			unauthenticated = append(unauthenticated, r.URL.Path)
+		}
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v3/repos/") && strings.HasSuffix(r.URL.Path, "/branches/main"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"commit":{"sha":"` + commit + `"}}`))
+		case strings.HasPrefix(r.URL.Path, "/raw/") && strings.HasSuffix(r.URL.Path, "/main/rule.mdc"):
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("enterprise rule\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	ctx := WithHTTPClient(context.Background(), &HTTPClient{client: server.Client()})
+
+	ghe := &GitHubHost{WebURL: server.URL, Token: token, Default: true}
+	if err := ghe.normalize(); err != nil {
+		t.Fatalf("normalize returned error: %v", err)
+	}
+	setGitHubHosts(t, ghe)
+
+	blobURL := server.URL + "/team/rules/blob/main/rule.mdc"
+	rule, err := handleGitHubBlob(ctx, cursorDir, blobURL)
+	if err != nil {
+		t.Fatalf("handleGitHubBlob returned error: %v", err)
+	}
+	if rule.ResolvedCommit != commit {
+		t.Errorf("Expected resolved commit %s, got %s", commit, rule.ResolvedCommit)
+	}
+
+	rule, err = handleUsernameRule(ctx, cursorDir, "team/rule")
+	if err != nil {
+		t.Fatalf("handleUsernameRule returned error: %v", err)
+	}
+	expectedURL := server.URL + "/team/cursor-rules-collection/blob/main/rule.mdc"
+	if rule.SourceURL != expectedURL {
+		t.Errorf("Expected source URL %s, got %s", expectedURL, rule.SourceURL)
+	}
+
+	if len(unauthenticated) > 0 {
+		t.Errorf("Requests without the host token: %v", unauthenticated)
+	}
+}
+
+// TestGitHubRequestToken tests that a host's token is only sent to the scheme, host name and
+// port of its API and raw URLs, and over http only when the host allows it.
+func TestGitHubRequestToken(t *testing.T) {
+	ghe := &GitHubHost{WebURL: "https://ghe.example.com", Token: "ghe-token"}
+	insecure := &GitHubHost{WebURL: "http://insecure.example.com", Token: "insecure-token", AllowInsecure: true}
+	for _, host := range []*GitHubHost{ghe, insecure} {
+		if err := host.normalize(); err != nil {
+			t.Fatalf("normalize returned error: %v", err)
+		}
+	}
+	setGitHubHosts(t, ghe, insecure)
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://ghe.example.com/api/v3/repos/team/rules", "Bearer ghe-token"},
+		{"https://ghe.example.com:443/raw/team/rules/main/rule.mdc", "Bearer ghe-token"},
+		{"http://ghe.example.com/api/v3/repos/team/rules", ""},
+		{"https://ghe.example.com:8443/api/v3/repos/team/rules", ""},
+		{"https://other.example.com/api/v3/repos/team/rules", ""},
+		{"http://insecure.example.com/api/v3/repos/team/rules", "Bearer insecure-token"},
+		{"http://insecure.example.com:8080/api/v3/repos/team/rules", ""},
+	}
+
+	for _, tt := range tests {
+		req, err := newGitHubRequest(context.Background(), tt.url)
+		if err != nil {
+			t.Fatalf("newGitHubRequest(%s) returned error: %v", tt.url, err)
+		}
+		if auth := req.Header.Get("Authorization"); auth != tt.expected {
+			t.Errorf("%s: expected Authorization %q, got %q", tt.url, tt.expected, auth)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// httpCacheMaxAge is how long a cached response is kept after it was last used.
+	httpCacheMaxAge = 30 * 24 * time.Hour
+
+	// httpCacheMaxSize bounds the response cache; the least recently used entries are
+	// removed first.
+	httpCacheMaxSize = 256 << 20
+)
+
+// pruneHTTPCacheOnce prunes the response cache at most once per run, before the first
+// response is cached.
+var pruneHTTPCacheOnce sync.Once
+
+// httpCacheEntry is a cached response together with the validators that let a later request
+// for the same URL be answered with 304 Not Modified. Entries without validators are only
+// used in offline mode.
+type httpCacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+// getHTTPCachePath returns where the cached response for a URL is stored.
+func getHTTPCachePath(requestURL string) (string, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := calculateSHA256([]byte(requestURL))
+	return filepath.Join(cacheDir, "http", hash[:2], hash+".json"), nil
+}
+
+// loadHTTPCacheEntry returns the cached response for a URL, or nil if there is none.
+func loadHTTPCacheEntry(requestURL string) *httpCacheEntry {
+	path, err := getHTTPCachePath(requestURL)
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != requestURL {
+		Debugf("Ignoring invalid HTTP cache entry %s\n", path)
+		return nil
+	}
+
+	// The modification time records the last use, which pruning goes by
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return &entry
+}
+
+// loadHTTPCacheEntries returns every valid entry in the response cache.
+func loadHTTPCacheEntries() []*httpCacheEntry {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil
+	}
+
+	var entries []*httpCacheEntry
+	_ = filepath.WalkDir(filepath.Join(cacheDir, "http"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var entry httpCacheEntry
+		if json.Unmarshal(data, &entry) == nil && entry.URL != "" {
+			entries = append(entries, &entry)
+		}
+		return nil
+	})
+	return entries
+}
+
+// saveHTTPCacheEntry stores a response in the cache.
+func saveHTTPCacheEntry(entry *httpCacheEntry) error {
+	path, err := getHTTPCachePath(entry.URL)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	pruneHTTPCacheOnce.Do(func() {
+		pruneHTTPCache(filepath.Dir(filepath.Dir(path)), httpCacheMaxAge, httpCacheMaxSize, time.Now())
+	})
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create HTTP cache directory: %w", err)
+	}
+	// Responses may hold private repository content
+	return writeFileAtomic(path, data, 0o600)
+}
+
+// pruneHTTPCache removes the entries in dir that weren't used within maxAge, then the least
+// recently used ones until the rest takes at most maxSize bytes.
+func pruneHTTPCache(dir string, maxAge time.Duration, maxSize int64, now time.Time) {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheFile
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, cacheFile{path, info.Size(), info.ModTime()})
+		return nil
+	})
+
+	// Newest first, so everything past the size budget can go
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	var total int64
+	for _, f := range files {
+		if now.Sub(f.modTime) <= maxAge && total+f.size <= maxSize {
+			total += f.size
+			continue
+		}
+		// Once the budget is used up, every older entry goes too
+		total = maxSize
+		if err := os.Remove(f.path); err != nil {
+			Debugf("Failed to prune HTTP cache entry %s: %v\n", f.path, err)
+			continue
+		}
+		Debugf("Pruned HTTP cache entry %s\n", f.path)
+	}
+}
+
+// response turns a cache entry back into a 200 response to req.
+func (e *httpCacheEntry) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// checkedResponse turns a cache entry back into a 200 response to req after checking its
+// body with read, since the limits may have changed since it was cached.
+func (e *httpCacheEntry) checkedResponse(req *http.Request, read func(resp *http.Response, sourceURL string) ([]byte, error)) (*http.Response, error) {
+	if _, err := read(e.response(req), e.URL); err != nil {
+		return nil, err
+	}
+	return e.response(req), nil
+}
+
+// doGitHubRequest sends a request created by newGitHubRequest through the response cache.
+// A 304 Not Modified, which doesn't count against the GitHub rate limit, is answered from
+// the cache.
+func doGitHubRequest(req *http.Request) (*http.Response, error) {
+	return doCachedRequest(req, sendGitHubRequest, readAPIResponse)
+}
+
+// doCachedRequest sends a GET request with send and caches successful responses whose body
+// read accepts. Later requests for the same URL are sent with If-None-Match or
+// If-Modified-Since when the cached response had an ETag or Last-Modified header. In
+// offline mode nothing is sent: the response comes from the cache, or the request fails
+// with ErrOfflineCacheMiss.
+func doCachedRequest(req *http.Request, send func(*http.Request) (*http.Response, error),
+	read func(resp *http.Response, sourceURL string) ([]byte, error)) (*http.Response, error) {
+	requestURL := req.URL.String()
+	cached := loadHTTPCacheEntry(requestURL)
+
+	if isOffline() {
+		if cached == nil {
+			return nil, &ErrOfflineCacheMiss{Item: redactSecrets(requestURL)}
+		}
+		Debugf("Offline, using cached response for %s\n", requestURL)
+		return cached.checkedResponse(req, read)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		Debugf("Not modified, using cached response for %s\n", requestURL)
+		return cached.checkedResponse(req, read)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	// Only responses that pass the checks are cached
+	body, err := read(resp, requestURL)
+	resp.Body.Close()
+	if err != nil {
+		if IsInvalidDownloadError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read response from %s: %w", redactSecrets(requestURL), err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := &httpCacheEntry{
+		URL:          requestURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         body,
+	}
+	if err := saveHTTPCacheEntry(entry); err != nil {
+		Debugf("Failed to cache response for %s: %v\n", requestURL, err)
+	}
+	return resp, nil
+}
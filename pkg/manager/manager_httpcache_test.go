@@ -0,0 +1,159 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDoGitHubRequestCache tests that repeated requests are conditional and that a 304
+// response is answered from the cache.
+func TestDoGitHubRequestCache(t *testing.T) {
+	const commit = "6666666666666666666666666666666666666666"
+
+	tests := []struct {
+		name        string
+		validator   string
+		value       string
+		conditional string
+	}{
+		{"etag", "ETag", `"abc123"`, "If-None-Match"},
+		{"last modified", "Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT", "If-Modified-Since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullResponses, notModified := 0, 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/owner/repo/branches/main", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(tt.conditional) == tt.value {
+					notModified++
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				fullResponses++
+				w.Header().Set(tt.validator, tt.value)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"commit":{"sha":"` + commit + `"}}`))
+			})
+			ctx := startTestGitHubServer(t, mux)
+
+			for i := 0; i < 3; i++ {
+				sha, err := getHeadCommitForBranch(ctx, githubDotCom, "owner", "repo", "main")
+				if err != nil {
+					t.Fatalf("getHeadCommitForBranch returned error: %v", err)
+				}
+				if sha != commit {
+					t.Errorf("Request %d: expected sha %s, got %s", i+1, commit, sha)
+				}
+			}
+
+			if fullResponses != 1 || notModified != 2 {
+				t.Errorf("Expected 1 full and 2 not-modified responses, got %d and %d", fullResponses, notModified)
+			}
+		})
+	}
+}
+
+// TestDoGitHubRequestNoValidators tests that responses without validators are requested
+// unconditionally, and only kept for offline mode.
+func TestDoGitHubRequestNoValidators(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/owner/repo/main/rule.mdc", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			t.Error("Unexpected conditional request")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("rule\n"))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	for i := 0; i < 2; i++ {
+		content, err := fetchGitHubRaw(ctx, githubDotCom, "owner", "repo", "main", "rule.mdc")
+		if err != nil {
+			t.Fatalf("fetchGitHubRaw returned error: %v", err)
+		}
+		if string(content) != "rule\n" {
+			t.Errorf("Unexpected content %q", content)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	entry := loadHTTPCacheEntry(githubDotCom.RawURL + "/owner/repo/main/rule.mdc")
+	if entry == nil || string(entry.Body) != "rule\n" {
+		t.Errorf("Expected the response to be cached for offline use, got %+v", entry)
+	}
+}
+
+// TestPruneHTTPCache tests that entries unused for too long are removed, then the least
+// recently used ones until the cache fits its size budget.
+func TestPruneHTTPCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	entries := []struct {
+		name string
+		age  time.Duration
+		kept bool
+	}{
+		{"new.json", time.Hour, true},
+		{"recent.json", 2 * time.Hour, true},
+		{"older.json", 3 * time.Hour, false},
+		{"expired.json", 48 * time.Hour, false},
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, "ab", e.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create cache directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, 10), 0o600); err != nil {
+			t.Fatalf("Failed to write cache entry: %v", err)
+		}
+		modTime := now.Add(-e.age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	pruneHTTPCache(dir, 24*time.Hour, 25, now)
+
+	for _, e := range entries {
+		if kept := fileExists(filepath.Join(dir, "ab", e.name)); kept != e.kept {
+			t.Errorf("%s: expected kept=%v, got %v", e.name, e.kept, kept)
+		}
+	}
+}
+
+// TestLoadShareableFromURLNotCached tests that share URLs, which may carry a token, are
+// neither cached nor served from the cache offline.
+func TestLoadShareableFromURLNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"formatVersion":1}`))
+	}))
+	t.Cleanup(server.Close)
+	shareURL := server.URL + "/share.json?token=secret"
+
+	data, err := loadShareableFromURL(context.Background(), shareURL)
+	if err != nil {
+		t.Fatalf("loadShareableFromURL returned error: %v", err)
+	}
+	if string(data) != `{"formatVersion":1}` {
+		t.Errorf("Unexpected share data %q", data)
+	}
+	if entry := loadHTTPCacheEntry(shareURL); entry != nil {
+		t.Errorf("Expected the share URL not to be cached, got %+v", entry)
+	}
+
+	setOffline(t)
+	if _, err := loadShareableFromURL(context.Background(), shareURL); !IsOfflineCacheMissError(err) {
+		t.Errorf("Expected an offline cache miss, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults of the HTTP client settings in the user config.
+const (
+	DefaultHTTPTimeout        = 60 * time.Second
+	DefaultHTTPConnectTimeout = 10 * time.Second
+	DefaultHTTPRetries        = 3
+)
+
+// Backoff before the first retry; it doubles with every further attempt up to maxRetryBackoff.
+const (
+	baseRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff  = 8 * time.Second
+)
+
+// HTTPConfig holds the "http" settings of the user config.
+type HTTPConfig struct {
+	// Limit for a whole request including reading the body, e.g. "60s"
+	Timeout string `json:"timeout,omitempty"`
+
+	// Limit for establishing a connection, e.g. "10s"
+	ConnectTimeout string `json:"connectTimeout,omitempty"`
+
+	// How often a request is retried after a network error or a 5xx response
+	Retries *int `json:"retries,omitempty"`
+
+	// Proxy URL for all requests; HTTPS_PROXY and the related variables apply when unset
+	Proxy string `json:"proxy,omitempty"`
+
+	// PEM file with certificate authorities to trust in addition to the system ones
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+// HTTPClient sends the requests cursor-rules makes. It retries requests which failed with
+// a network error or a 5xx response, waiting with exponential backoff and jitter in between.
+type HTTPClient struct {
+	client  *http.Client
+	retries int
+}
+
+// httpClientKey is the context key of the client set with WithHTTPClient.
+type httpClientKey struct{}
+
+// WithHTTPClient returns a copy of ctx whose requests are sent with client.
+func WithHTTPClient(ctx context.Context, client *HTTPClient) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+// defaultHTTPClient creates the client with the default timeouts and retries the first
+// time a context without a client is used.
+var defaultHTTPClient = sync.OnceValues(func() (*HTTPClient, error) {
+	return newHTTPClient(HTTPConfig{})
+})
+
+// httpClientFrom returns the client set on ctx with WithHTTPClient, or one with the
+// default settings.
+func httpClientFrom(ctx context.Context) (*HTTPClient, error) {
+	if client, ok := ctx.Value(httpClientKey{}).(*HTTPClient); ok && client != nil {
+		return client, nil
+	}
+	client, err := defaultHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	return client, nil
+}
+
+// retrySleep waits for d or until ctx is done. It is a variable so tests don't have to wait.
+var retrySleep = rateLimitSleep
+
+// newHTTPClient creates a client from the http settings of the user config.
+func newHTTPClient(config HTTPConfig) (*HTTPClient, error) {
+	timeout, err := parseConfigDuration("http.timeout", config.Timeout, DefaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout, err := parseConfigDuration("http.connectTimeout", config.ConnectTimeout, DefaultHTTPConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := DefaultHTTPRetries
+	if config.Retries != nil {
+		if *config.Retries < 0 {
+			return nil, fmt.Errorf("invalid http.retries: %d is negative", *config.Retries)
+		}
+		retries = *config.Retries
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid http.proxy: %q is not a URL", config.Proxy)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read http.caBundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid http.caBundle: no PEM certificates in %s", config.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: connectTimeout,
+		MaxIdleConnsPerHost: DefaultConcurrency,
+		IdleConnTimeout:     90 * time.Second,
+		ForceAttemptHTTP2:   true,
+	}
+
+	return &HTTPClient{
+		client:  &http.Client{Transport: transport, Timeout: timeout},
+		retries: retries,
+	}, nil
+}
+
+// parseConfigDuration parses a duration setting, using def when it is empty.
+func parseConfigDuration(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s: %q is not a positive duration", name, value)
+	}
+	return d, nil
+}
+
+// LoadHTTPClient creates a client from the http settings of the user config. Requests are
+// sent with it once it is set on their context with WithHTTPClient.
+func LoadHTTPClient() (*HTTPClient, error) {
+	config, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newHTTPClient(config.HTTP)
+}
+
+// retryBackoff returns how long to wait before retry number attempt (starting at 0): a
+// random duration between half and all of the exponential backoff, so parallel downloads
+// don't retry in step.
+func retryBackoff(attempt int) time.Duration {
+	backoff := baseRetryBackoff << attempt
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff/2 + rand.N(backoff/2)
+}
+
+// isRetryableError reports whether a request error may be transient. Unknown hosts,
+// untrusted certificates and cancellation won't go away by trying again.
+func isRetryableError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// isRetryableStatus reports whether a response status is worth retrying.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Do sends a GET request, retrying network errors and 5xx responses. When the retries
+// run out, the last error or response is returned.
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+
+		retryable := false
+		if err != nil {
+			retryable = isRetryableError(err)
+		} else {
+			retryable = isRetryableStatus(resp.StatusCode)
+		}
+		if !retryable || attempt >= c.retries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			Debugf("Request to %s failed, retrying: %v\n", req.URL, err)
+		} else {
+			Debugf("Request to %s returned %s, retrying\n", req.URL, resp.Status)
+			resp.Body.Close()
+		}
+		if err := retrySleep(req.Context(), retryBackoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestHTTPClient creates a client with the given retries that doesn't wait between them,
+// and counts the waits.
+func newTestHTTPClient(t *testing.T, config HTTPConfig) (*HTTPClient, *int) {
+	t.Helper()
+	client, err := newHTTPClient(config)
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+
+	waits := 0
+	oldSleep := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		waits++
+		return nil
+	}
+	t.Cleanup(func() {
+		retrySleep = oldSleep
+	})
+	return client, &waits
+}
+
+// TestRetryingClient tests retrying 5xx responses and network errors.
+func TestRetryingClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		status         int
+		retries        int
+		expectedStatus int
+		expectedWaits  int
+	}{
+		{"transient bad gateway", 2, http.StatusBadGateway, 3, http.StatusOK, 2},
+		{"retries run out", 5, http.StatusServiceUnavailable, 2, http.StatusServiceUnavailable, 2},
+		{"no retries", 1, http.StatusInternalServerError, 0, http.StatusInternalServerError, 0},
+		{"not found is final", 1, http.StatusNotFound, 3, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := tt.failures
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if failures > 0 {
+					failures--
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			retries := tt.retries
+			client, waits := newTestHTTPClient(t, HTTPConfig{Retries: &retries})
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do returned error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if *waits != tt.expectedWaits {
+				t.Errorf("Expected %d retries, got %d", tt.expectedWaits, *waits)
+			}
+		})
+	}
+
+	t.Run("network error", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		serverURL := server.URL
+		server.Close()
+
+		client, waits := newTestHTTPClient(t, HTTPConfig{})
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, serverURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if _, err := client.Do(req); err == nil {
+			t.Fatal("Expected an error from a closed server, got nil")
+		}
+		if *waits != DefaultHTTPRetries {
+			t.Errorf("Expected %d retries, got %d", DefaultHTTPRetries, *waits)
+		}
+	})
+}
+
+// TestRetryBackoff tests that backoff grows exponentially with jitter and is capped.
+func TestRetryBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		limit := baseRetryBackoff << attempt
+		if limit > maxRetryBackoff {
+			limit = maxRetryBackoff
+		}
+		for i := 0; i < 20; i++ {
+			if d := retryBackoff(attempt); d < limit/2 || d >= limit {
+				t.Fatalf("Attempt %d: backoff %s outside [%s, %s)", attempt, d, limit/2, limit)
+			}
+		}
+	}
+}
+
+// TestNewHTTPClient tests validating the http settings of the user config, and trusting a
+// custom CA bundle.
+func TestNewHTTPClient(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer cleanupTestDir(t, tempDir)
+
+	negative := -1
+	invalid := []HTTPConfig{
+		{Timeout: "soon"},
+		{ConnectTimeout: "-5s"},
+		{Retries: &negative},
+		{Proxy: "proxy.example.com:8080"},
+		{CABundle: filepath.Join(tempDir, "missing.pem")},
+	}
+	for _, config := range invalid {
+		if _, err := newHTTPClient(config); err == nil {
+			t.Errorf("Expected error for %+v, got nil", config)
+		}
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	request := func(client *HTTPClient) error {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// The test server's certificate is only trusted once it's in the CA bundle
+	client, _ := newTestHTTPClient(t, HTTPConfig{})
+	if err := request(client); err == nil {
+		t.Error("Expected a certificate error without the CA bundle, got nil")
+	}
+
+	bundlePath := filepath.Join(tempDir, "ca.pem")
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundlePath, bundle, 0o600); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+	client, waits := newTestHTTPClient(t, HTTPConfig{CABundle: bundlePath, Timeout: "5s"})
+	if err := request(client); err != nil {
+		t.Errorf("Request with the CA bundle failed: %v", err)
+	}
+	if *waits != 0 {
+		t.Errorf("Expected no retries, got %d", *waits)
+	}
+}
+
+// TestHTTPClientFrom tests that requests use the client set on their context, and a shared
+// default client otherwise.
+func TestHTTPClientFrom(t *testing.T) {
+	client, _ := newTestHTTPClient(t, HTTPConfig{})
+	got, err := httpClientFrom(WithHTTPClient(context.Background(), client))
+	if err != nil || got != client {
+		t.Errorf("Expected the client set on the context, got %p (%v)", got, err)
+	}
+
+	first, err := httpClientFrom(context.Background())
+	if err != nil {
+		t.Fatalf("httpClientFrom returned error: %v", err)
+	}
+	second, _ := httpClientFrom(context.Background())
+	if first == client || first != second {
+		t.Error("Expected one default client for contexts without a client")
+	}
+}
@@ -0,0 +1,336 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestFileName is the hand-edited file that declares which rules a project wants
+// (similar to a package.json).
+const ManifestFileName = "cursor-rules.json"
+
+// Manifest represents the structure of the manifest file on disk.
+type Manifest struct {
+	// References to install, in any format accepted by 'add':
+	// local paths, GitHub URLs, username/rule@tag shorthands or glob patterns
+	Rules []string `json:"rules"`
+}
+
+// InstallResult summarizes the changes made by Install.
+type InstallResult struct {
+	// References that were installed because they were missing
+	Added []string
+
+	// Rule keys that were removed because they are no longer listed in the manifest
+	Removed []string
+
+	// References that were already installed
+	Unchanged []string
+}
+
+// getManifestPath returns the path to the manifest in the project root.
+func getManifestPath(cursorDir string) string {
+	return filepath.Join(getRootDirectory(cursorDir), ManifestFileName)
+}
+
+// LoadManifest loads the manifest from the project root.
+func LoadManifest(cursorDir string) (*Manifest, error) {
+	manifestPath := getManifestPath(cursorDir)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no %s found in %s", ManifestFileName, getRootDirectory(cursorDir))
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	// Reject duplicate references early, they would fight over the same rule
+	seen := make(map[string]bool, len(manifest.Rules))
+	for _, ref := range manifest.Rules {
+		if ref == "" {
+			return nil, errors.New("manifest contains an empty reference")
+		}
+		if seen[ref] {
+			return nil, fmt.Errorf("manifest lists %q more than once", ref)
+		}
+		seen[ref] = true
+	}
+
+	return &manifest, nil
+}
+
+// ruleMatchesReference checks if a lockfile entry was installed from the given reference.
+func ruleMatchesReference(rule RuleSource, ref string) bool {
+	if rule.Reference == ref || rule.GlobPattern == ref {
+		return true
+	}
+
+	// The default username handler stores the resolved "username/rule" reference
+	if defaultUsername := getDefaultUsername(); defaultUsername != "" {
+		return rule.Reference == defaultUsername+"/"+ref
+	}
+
+	return false
+}
+
+// isManifestRule reports whether a lockfile entry is expected to be listed in the manifest.
+// Built-in rules and unmanaged files are not installed from references.
+func isManifestRule(rule RuleSource) bool {
+	return rule.SourceType != SourceTypeBuiltIn && rule.SourceType != SourceTypeUnmanaged
+}
+
+// ruleFilesExist checks if every file of a rule is present in .cursor/rules.
+func ruleFilesExist(cursorDir string, rule RuleSource) bool {
+	for _, file := range rule.LocalFiles {
+		if !fileExists(ruleFilePath(cursorDir, file)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Install reconciles .cursor/rules with the manifest: it installs every listed
+// reference that is missing, removes rules that are no longer listed and
+// updates the lockfile accordingly.
+func Install(ctx context.Context, cursorDir string) (*InstallResult, error) {
+	manifest, err := LoadManifest(cursorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	result := &InstallResult{}
+
+	// Remove rules that no manifest entry accounts for. Built-in rules are added from
+	// templates rather than the manifest, and adopted files without a known source would
+	// have no way back, so both are kept
+	for _, rule := range lock.Rules {
+		if !isManifestRule(rule) {
+			continue
+		}
+
+		listed := false
+		for _, ref := range manifest.Rules {
+			if ruleMatchesReference(rule, ref) {
+				listed = true
+				break
+			}
+		}
+		if listed {
+			continue
+		}
+
+		if err := RemoveRule(cursorDir, rule.Key); err != nil {
+			return result, fmt.Errorf("failed to remove rule %s: %w", rule.Key, err)
+		}
+		result.Removed = append(result.Removed, rule.Key)
+	}
+
+	// Install references that are missing from the lockfile or from disk
+	for _, ref := range manifest.Rules {
+		installed := false
+		for _, rule := range lock.Rules {
+			if ruleMatchesReference(rule, ref) && ruleFilesExist(cursorDir, rule) {
+				installed = true
+				break
+			}
+		}
+		if installed {
+			result.Unchanged = append(result.Unchanged, ref)
+			continue
+		}
+
+		if err := AddRuleByReferenceContext(ctx, cursorDir, ref); err != nil {
+			return result, fmt.Errorf("failed to install %q: %w", ref, err)
+		}
+		result.Added = append(result.Added, ref)
+	}
+
+	return result, nil
+}
+
+// checkManifestInSync reports references that a frozen install would have to add
+// to or remove from the lockfile.
+func checkManifestInSync(manifest *Manifest, lock *LockFile) error {
+	var problems []string
+
+	for _, ref := range manifest.Rules {
+		locked := false
+		for _, rule := range lock.Rules {
+			if ruleMatchesReference(rule, ref) {
+				locked = true
+				break
+			}
+		}
+		if !locked {
+			problems = append(problems, fmt.Sprintf("%q is listed in the manifest but not locked", ref))
+		}
+	}
+
+	for _, rule := range lock.Rules {
+		if !isManifestRule(rule) {
+			continue
+		}
+
+		listed := false
+		for _, ref := range manifest.Rules {
+			if ruleMatchesReference(rule, ref) {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			problems = append(problems, fmt.Sprintf("%s is locked but not listed in the manifest", rule.Key))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("lockfile is out of date with %s:\n  %s", ManifestFileName, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// fetchLockedContent fetches the content of a rule exactly as recorded in the lockfile,
+// without writing anything to disk.
+func fetchLockedContent(ctx context.Context, cursorDir string, rule RuleSource) ([]byte, error) {
+	switch rule.SourceType {
+	case SourceTypeGitHubFile, SourceTypeGitHubShorthand, SourceTypeGitHubRepoPath:
+		sourceURL := rule.SourceURL
+		if sourceURL == "" && rule.SourceType == SourceTypeGitHubFile {
+			sourceURL = rule.Reference
+		}
+
+		host, owner, repo, gitRef, path, ok := parseGitHubBlobURL(sourceURL)
+		if !ok {
+			return nil, fmt.Errorf("rule %s has no recorded GitHub source URL, re-add it to record one", rule.Key)
+		}
+
+		// Content already cached under the locked hash needs no download, which also lets
+		// frozen installs run offline
+		if rule.ContentSHA256 != "" {
+			if content, err := loadCachedContent(rule.ContentSHA256); err == nil {
+				return content, nil
+			}
+		}
+
+		// Branch references are pinned to the commit they resolved to
+		if rule.ResolvedCommit != "" {
+			gitRef = rule.ResolvedCommit
+		}
+
+		return fetchGitHubRaw(ctx, host, owner, repo, gitRef, path)
+
+	case SourceTypeLocalAbs, SourceTypeLocalRel:
+		path := resolveLocalReference(cursorDir, rule.Reference)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, &ErrLocalFileAccess{Path: path, Cause: err}
+		}
+		return data, nil
+
+	case SourceTypeBuiltIn:
+		content, err := getBuiltInTemplate(rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template: %w", err)
+		}
+		return []byte(content), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported source type for frozen install: %s", rule.SourceType)
+	}
+}
+
+// InstallFrozen reinstalls every rule in the lockfile at its recorded version and checks
+// the content against the recorded hashes. Nothing is written unless every rule could be
+// fetched and verified, and the lockfile itself is never modified.
+func InstallFrozen(ctx context.Context, cursorDir string) (*InstallResult, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	// If the project has a manifest, it must agree with the lockfile
+	if fileExists(getManifestPath(cursorDir)) {
+		manifest, err := LoadManifest(cursorDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkManifestInSync(manifest, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch and verify everything before touching the filesystem
+	type frozenFile struct {
+		path    string
+		content []byte
+	}
+	files := make([]frozenFile, 0, len(lock.Rules))
+	result := &InstallResult{}
+
+	// Directory rules are fetched and verified file by file
+	var rules []RuleSource
+	for _, rule := range lock.Rules {
+		rules = append(rules, ruleFileSources(rule)...)
+	}
+
+	for _, rule := range rules {
+		if len(rule.LocalFiles) != 1 {
+			return nil, fmt.Errorf("rule %s has %d files, frozen install supports exactly one",
+				rule.Key, len(rule.LocalFiles))
+		}
+		targetPath := ruleFilePath(cursorDir, rule.LocalFiles[0])
+
+		// Unmanaged entries, and built-in entries without a category recorded by older
+		// versions, were adopted from files found on disk and have no source to reinstall from
+		if rule.SourceType == SourceTypeUnmanaged || (rule.SourceType == SourceTypeBuiltIn && rule.Category == "") {
+			if !fileExists(targetPath) {
+				return nil, fmt.Errorf("rule %s has no source to reinstall from and is missing on disk", rule.Key)
+			}
+			result.Unchanged = append(result.Unchanged, rule.Key)
+			continue
+		}
+
+		content, err := fetchLockedContent(ctx, cursorDir, rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch rule %s: %w", rule.Key, err)
+		}
+
+		// Built-in rules locked by older versions have no hash; any recorded hash is checked
+		if rule.ContentSHA256 == "" && rule.SourceType != SourceTypeBuiltIn {
+			return nil, fmt.Errorf("rule %s has no content hash in the lockfile", rule.Key)
+		}
+		if rule.ContentSHA256 != "" {
+			if actual := calculateSHA256(content); actual != rule.ContentSHA256 {
+				return nil, &ErrContentMismatch{RuleKey: rule.Key, Expected: rule.ContentSHA256, Actual: actual}
+			}
+		}
+
+		files = append(files, frozenFile{path: targetPath, content: content})
+		if n := len(result.Added); n == 0 || result.Added[n-1] != rule.Key {
+			result.Added = append(result.Added, rule.Key)
+		}
+	}
+
+	for _, f := range files {
+		if err := writeRuleFile(f.path, f.content, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write rule file: %w", err)
+		}
+	}
+
+	return result, nil
+}
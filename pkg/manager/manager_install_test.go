@@ -0,0 +1,234 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestManifest writes a manifest listing the given references into the project root.
+func writeTestManifest(t *testing.T, cursorDir string, refs ...string) {
+	t.Helper()
+	data, err := json.Marshal(Manifest{Rules: refs})
+	if err != nil {
+		t.Fatalf("Failed to serialize manifest: %v", err)
+	}
+	if err := os.WriteFile(getManifestPath(cursorDir), data, 0o644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+}
+
+// TestInstall tests that Install reconciles .cursor/rules with the manifest.
+func TestInstall(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	// Create two local rule files to reference from the manifest
+	ruleA := filepath.Join(tempDir, "rule-a.mdc")
+	ruleB := filepath.Join(tempDir, "rule-b.mdc")
+	for _, path := range []string{ruleA, ruleB} {
+		if err := os.WriteFile(path, []byte("# "+filepath.Base(path)), 0o644); err != nil {
+			t.Fatalf("Failed to write test rule: %v", err)
+		}
+	}
+
+	// No manifest yet
+	if _, err := Install(context.Background(), cursorDir); err == nil {
+		t.Fatal("Expected error when no manifest exists, got nil")
+	}
+
+	// Initial install adds both rules
+	writeTestManifest(t, cursorDir, ruleA, ruleB)
+	result, err := Install(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Added) != 2 || len(result.Removed) != 0 {
+		t.Errorf("Expected 2 added and 0 removed, got %+v", result)
+	}
+
+	// Running again is a no-op
+	result, err = Install(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Added) != 0 || len(result.Unchanged) != 2 {
+		t.Errorf("Expected 2 unchanged rules on second install, got %+v", result)
+	}
+
+	// Dropping a reference from the manifest removes the rule and its file
+	writeTestManifest(t, cursorDir, ruleA)
+	result, err = Install(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	keyB := generateRuleKey(ruleB)
+	if len(result.Removed) != 1 || result.Removed[0] != keyB {
+		t.Errorf("Expected %s to be removed, got %+v", keyB, result)
+	}
+	if fileExists(filepath.Join(cursorDir, keyB+".mdc")) {
+		t.Errorf("Rule file for %s was not removed", keyB)
+	}
+
+	// A rule whose file was deleted is reinstalled
+	keyA := generateRuleKey(ruleA)
+	if err := os.Remove(filepath.Join(cursorDir, keyA+".mdc")); err != nil {
+		t.Fatalf("Failed to remove rule file: %v", err)
+	}
+	result, err = Install(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Added) != 1 {
+		t.Errorf("Expected missing rule to be reinstalled, got %+v", result)
+	}
+	if !fileExists(filepath.Join(cursorDir, keyA+".mdc")) {
+		t.Errorf("Rule file for %s was not restored", keyA)
+	}
+}
+
+// TestInstall_KeepsBuiltInRules tests that Install doesn't remove built-in rules, which are
+// added from templates rather than listed in the manifest.
+func TestInstall_KeepsBuiltInRules(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	setupTestTemplates()
+
+	if err := AddRule(cursorDir, "general", "test-rule"); err != nil {
+		t.Fatalf("AddRule returned error: %v", err)
+	}
+
+	writeTestManifest(t, cursorDir)
+	result, err := Install(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("Expected no rules to be removed, got %v", result.Removed)
+	}
+	if !fileExists(filepath.Join(cursorDir, "test-rule.mdc")) {
+		t.Error("Built-in rule file was removed")
+	}
+
+	// A frozen install doesn't expect the built-in rule in the manifest either
+	if _, err := InstallFrozen(context.Background(), cursorDir); err != nil {
+		t.Errorf("InstallFrozen returned error: %v", err)
+	}
+}
+
+// TestInstallFrozen_BuiltInHash tests that a frozen install checks built-in rules against
+// their recorded hash.
+func TestInstallFrozen_BuiltInHash(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	setupTestTemplates()
+
+	if err := AddRule(cursorDir, "general", "test-rule"); err != nil {
+		t.Fatalf("AddRule returned error: %v", err)
+	}
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	lock.Rules[0].ContentSHA256 = calculateSHA256([]byte("older template content"))
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	_, err = InstallFrozen(context.Background(), cursorDir)
+	if !IsContentMismatchError(err) {
+		t.Errorf("Expected ErrContentMismatch for a changed built-in template, got %v", err)
+	}
+}
+
+// TestInstallFrozen_RelativeReference tests that relative local references in the lockfile
+// are read from the project root rather than the working directory.
+func TestInstallFrozen_RelativeReference(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	content := []byte("# Relative rule")
+	if err := os.MkdirAll(filepath.Join(tempDir, "rules"), 0o755); err != nil {
+		t.Fatalf("Failed to create rules directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "rules", "rel.mdc"), content, 0o644); err != nil {
+		t.Fatalf("Failed to write test rule: %v", err)
+	}
+
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:           "rules/rel",
+		SourceType:    SourceTypeLocalRel,
+		Reference:     "rules/rel.mdc",
+		LocalFiles:    []string{"rules/rel.mdc"},
+		ContentSHA256: calculateSHA256(content),
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	if _, err := InstallFrozen(context.Background(), cursorDir); err != nil {
+		t.Fatalf("InstallFrozen returned error: %v", err)
+	}
+	if !fileExists(filepath.Join(cursorDir, "rules", "rel.mdc")) {
+		t.Error("InstallFrozen did not install the relative rule")
+	}
+}
+
+// TestLoadManifest_Duplicates tests that duplicate references are rejected.
+func TestLoadManifest_Duplicates(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	writeTestManifest(t, cursorDir, "user/rule", "user/rule")
+
+	if _, err := LoadManifest(cursorDir); err == nil {
+		t.Error("Expected error for duplicate manifest entries, got nil")
+	}
+}
+
+// TestInstallFrozen tests that a frozen install restores locked rules and rejects drift.
+func TestInstallFrozen(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	rulePath := filepath.Join(tempDir, "rule.mdc")
+	if err := os.WriteFile(rulePath, []byte("# Rule"), 0o644); err != nil {
+		t.Fatalf("Failed to write test rule: %v", err)
+	}
+
+	writeTestManifest(t, cursorDir, rulePath)
+	if _, err := Install(context.Background(), cursorDir); err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+
+	// Delete the installed file and restore it from the lockfile
+	installedPath := filepath.Join(cursorDir, generateRuleKey(rulePath)+".mdc")
+	if err := os.Remove(installedPath); err != nil {
+		t.Fatalf("Failed to remove installed rule: %v", err)
+	}
+	if _, err := InstallFrozen(context.Background(), cursorDir); err != nil {
+		t.Fatalf("InstallFrozen returned error: %v", err)
+	}
+	if !fileExists(installedPath) {
+		t.Fatalf("InstallFrozen did not restore %s", installedPath)
+	}
+
+	// Changed source content must be rejected without writing anything
+	if err := os.Remove(installedPath); err != nil {
+		t.Fatalf("Failed to remove installed rule: %v", err)
+	}
+	if err := os.WriteFile(rulePath, []byte("# Changed rule"), 0o644); err != nil {
+		t.Fatalf("Failed to modify test rule: %v", err)
+	}
+	_, err := InstallFrozen(context.Background(), cursorDir)
+	if !IsContentMismatchError(err) {
+		t.Errorf("Expected ErrContentMismatch, got %v", err)
+	}
+	if fileExists(installedPath) {
+		t.Errorf("InstallFrozen wrote files despite a hash mismatch")
+	}
+
+	// A manifest entry missing from the lockfile must be rejected
+	writeTestManifest(t, cursorDir, rulePath, "user/other-rule")
+	if _, err := InstallFrozen(context.Background(), cursorDir); err == nil {
+		t.Error("Expected error when the manifest and lockfile disagree, got nil")
+	}
+}
@@ -86,7 +86,7 @@ func processLocalFile(cursorDir, filePath string, isAbs bool) (RuleSource, error
 	}
 
 	// 4. Write to .cursor/rules
-	err = os.WriteFile(destPath, data, 0o600)
+	err = writeRuleFile(destPath, data, 0o600)
 	if err != nil {
 		return RuleSource{}, &ErrLocalFileAccess{
 			Path:  destPath,
@@ -117,8 +117,6 @@ func processLocalFile(cursorDir, filePath string, isAbs bool) (RuleSource, error
 		LocalFiles: []string{destFilename},
 		// Calculate and store content hash for future modification checks
 		ContentSHA256: calculateSHA256(data),
-		// Store the original glob pattern
-		GlobPattern: filePath,
 	}
 
 	return result, nil
@@ -11,18 +11,27 @@ import (
 // LockFileName is the file that tracks installed rules (similar to a package-lock.json).
 const LockFileName = "cursor-rules.lock"
 
-// This can be set through an environment variable or config file.
-var UseRootLockFile = false
+// CurrentLockFileVersion is the lockfile schema version written by this build.
+// Older lockfiles are migrated on load, see manager_lockfile_migrate.go.
+const CurrentLockFileVersion = 2
 
 // LockFile represents the structure of the lockfile on disk
 type LockFile struct {
-	// For backwards compatibility
-	Installed []string `json:"installed,omitempty"`
+	// Schema version of the lockfile
+	Version int `json:"version"`
 
-	// Enhanced structure for tracking rule sources
+	// Installed rules. LocalFiles are always relative to .cursor/rules.
 	Rules []RuleSource `json:"rules"`
 }
 
+// newLockFile returns an empty lockfile at the current schema version.
+func newLockFile() *LockFile {
+	return &LockFile{
+		Version: CurrentLockFileVersion,
+		Rules:   []RuleSource{},
+	}
+}
+
 // getRootDirectory returns the project root directory from the cursor rules directory.
 func getRootDirectory(cursorDir string) string {
 	// cursorDir is typically /path/to/project/.cursor/rules
@@ -30,162 +39,214 @@ func getRootDirectory(cursorDir string) string {
 	return filepath.Dir(filepath.Dir(cursorDir))
 }
 
-// getLockFilePath returns the path to the lockfile based on the UseRootLockFile setting.
-func getLockFilePath(cursorDir string) string {
-	if UseRootLockFile {
-		rootDir := getRootDirectory(cursorDir)
-		return filepath.Join(rootDir, LockFileName)
+// getLockFilePaths returns the two possible lockfile locations.
+func getLockFilePaths(cursorDir string) (rulesPath, rootPath string) {
+	return filepath.Join(cursorDir, LockFileName), filepath.Join(getRootDirectory(cursorDir), LockFileName)
+}
+
+// getLockFilePath returns the path to the lockfile. An existing lockfile wins over
+// the configured location, and having one in both locations is an error.
+func getLockFilePath(cursorDir string) (string, error) {
+	rulesPath, rootPath := getLockFilePaths(cursorDir)
+	rulesExists := fileExists(rulesPath)
+	rootExists := fileExists(rootPath)
+
+	switch {
+	case rulesExists && rootExists:
+		return "", fmt.Errorf("found lockfiles in both %s and %s, remove one of them", rulesPath, rootPath)
+	case rulesExists:
+		return rulesPath, nil
+	case rootExists:
+		return rootPath, nil
 	}
-	return filepath.Join(cursorDir, LockFileName)
+
+	// No lockfile yet, use the configured location
+	config, err := LoadProjectConfig(cursorDir)
+	if err != nil {
+		return "", err
+	}
+	if config.UseRootLockFile() {
+		return rootPath, nil
+	}
+	return rulesPath, nil
 }
 
-// LoadLockFile loads the lockfile from disk, or creates a new one if it doesn't exist.
-func LoadLockFile(cursorDir string) (*LockFile, error) {
-	lockPath := getLockFilePath(cursorDir)
-	data, err := os.ReadFile(lockPath)
+// readLockFileDocument reads the raw lockfile from disk without migrating it.
+// It returns nil if no lockfile exists yet.
+func readLockFileDocument(cursorDir string) (*lockFileDocument, error) {
+	lockPath, err := getLockFilePath(cursorDir)
+	if err != nil {
+		return nil, err
+	}
 
+	data, err := os.ReadFile(lockPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Return an empty lockfile if it doesn't exist yet
-			return &LockFile{
-				Installed: []string{},
-				Rules:     []RuleSource{},
-			}, nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to read lockfile: %w", err)
 	}
 
-	var lock LockFile
-	err = json.Unmarshal(data, &lock)
-	if err != nil {
+	var doc lockFileDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
 	}
+	return &doc, nil
+}
 
-	// Migrate older lockfiles which only have the Installed field
-	if len(lock.Installed) > 0 && len(lock.Rules) == 0 {
-		migrated := make([]RuleSource, 0, len(lock.Installed))
-		for _, key := range lock.Installed {
-			migrated = append(migrated, RuleSource{
-				Key:        key,
-				SourceType: SourceTypeBuiltIn,
-				Reference:  key,
-				Category:   "",
-				LocalFiles: []string{filepath.Join(cursorDir, key+".mdc")},
-			})
-		}
-		lock.Rules = migrated
+// LoadLockFile loads the lockfile from disk, or creates a new one if it doesn't exist.
+// Lockfiles written by older versions are migrated to the current schema in memory.
+func LoadLockFile(cursorDir string) (*LockFile, error) {
+	doc, err := readLockFileDocument(cursorDir)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		// Return an empty lockfile if it doesn't exist yet
+		return newLockFile(), nil
 	}
 
-	return &lock, nil
+	if err := migrateLockFileDocument(cursorDir, doc); err != nil {
+		return nil, err
+	}
+
+	return &LockFile{
+		Version: doc.Version,
+		Rules:   doc.Rules,
+	}, nil
 }
 
-// Save writes the lockfile to disk.
+// Save writes the lockfile to disk. The data is written to a temporary file in the
+// same directory and renamed into place, so readers never see a partial lockfile.
 func (lock *LockFile) Save(cursorDir string) error {
-	lockPath := getLockFilePath(cursorDir)
+	lockPath, err := getLockFilePath(cursorDir)
+	if err != nil {
+		return err
+	}
+
+	// Always write the current schema
+	lock.Version = CurrentLockFileVersion
+	for i := range lock.Rules {
+		lock.Rules[i].LocalFiles = relativeLocalFiles(cursorDir, lock.Rules[i])
+	}
+
 	data, err := json.MarshalIndent(lock, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to serialize lockfile: %w", err)
 	}
 
-	err = os.WriteFile(lockPath, data, 0o644)
-	if err != nil {
+	if err := writeFileAtomic(lockPath, data, 0o644); err != nil {
 		return fmt.Errorf("failed to write lockfile: %w", err)
 	}
 	return nil
 }
 
-// SetLockFileLocation changes the lockfile location setting and ensures the lockfile exists.
-func SetLockFileLocation(cursorDir string, useRoot bool) (string, error) {
-	if useRoot == UseRootLockFile {
-		// Nothing to do if the setting isn't changing
-		return getLockFilePath(cursorDir), nil
+// writeFileAtomic writes data to a temporary file next to path and renames it over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
 	}
+	tmpPath := tmp.Name()
 
-	// Load the existing lockfile
-	oldUseRoot := UseRootLockFile
-	oldLockPath := getLockFilePath(cursorDir)
+	// Clean up the temporary file on any failure
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
 
-	// Change the setting temporarily to calculate the new path
-	UseRootLockFile = useRoot
-	newLockPath := getLockFilePath(cursorDir)
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	success = true
+	return nil
+}
 
-	// Check if the old lockfile exists
-	oldLockExists := false
-	if _, err := os.Stat(oldLockPath); err == nil {
-		oldLockExists = true
+// SetLockFileLocation stores the lockfile location in the project config and moves
+// an existing lockfile there. It returns the new lockfile path.
+func SetLockFileLocation(cursorDir string, useRoot bool) (string, error) {
+	config, err := LoadProjectConfig(cursorDir)
+	if err != nil {
+		return "", err
 	}
 
-	// Check if the new lockfile already exists
-	newLockExists := false
-	if _, err := os.Stat(newLockPath); err == nil {
-		newLockExists = true
+	rulesPath, rootPath := getLockFilePaths(cursorDir)
+	oldLockPath, newLockPath := rootPath, rulesPath
+	if useRoot {
+		oldLockPath, newLockPath = rulesPath, rootPath
 	}
 
+	oldLockExists := fileExists(oldLockPath)
+	newLockExists := fileExists(newLockPath)
+
 	// Handle potential conflicts
 	if oldLockExists && newLockExists {
 		// Both files exist, we'd need to merge them or make a decision
-		UseRootLockFile = oldUseRoot // Revert setting temporarily
-		return "", errors.New("cannot change lockfile location: both project and global lockfiles exist")
+		return "", errors.New("cannot change lockfile location: both project root and .cursor/rules lockfiles exist")
 	}
 
 	if oldLockExists {
 		// Read the old lockfile
 		data, err := os.ReadFile(oldLockPath)
 		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
 			return "", fmt.Errorf("failed to read existing lockfile: %w", err)
 		}
 
 		// Ensure the directory exists for the new location
 		err = os.MkdirAll(filepath.Dir(newLockPath), 0o755)
 		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
 			return "", fmt.Errorf("failed to create directory for new lockfile: %w", err)
 		}
 
 		// Write to the new location
-		err = os.WriteFile(newLockPath, data, 0o644)
+		err = writeFileAtomic(newLockPath, data, 0o644)
 		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
 			return "", fmt.Errorf("failed to write to new lockfile location: %w", err)
 		}
 
-		// Optionally, remove the old lockfile
+		// Remove the old lockfile so auto-detection finds only the new one
+		// and roll back the copy if that fails, two lockfiles would make it ambiguous
 		err = os.Remove(oldLockPath)
 		if err != nil {
-			// Non-critical error
-			fmt.Printf("Warning: Failed to remove old lockfile at %s: %v\n", oldLockPath, err)
-		}
-	} else if !newLockExists {
-		// Neither file exists, create a new empty lockfile
-		newLock := &LockFile{
-			Installed: []string{},
-			Rules:     []RuleSource{},
-		}
-
-		// Ensure the directory exists
-		err := os.MkdirAll(filepath.Dir(newLockPath), 0o755)
-		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
-			return "", fmt.Errorf("failed to create directory for new lockfile: %w", err)
+			if rmErr := os.Remove(newLockPath); rmErr != nil {
+				Debugf("SetLockFileLocation: failed to roll back %s: %v\n", newLockPath, rmErr)
+			}
+			return "", fmt.Errorf("failed to remove old lockfile at %s: %w", oldLockPath, err)
 		}
+	}
 
-		// Save the new empty lockfile
-		data, err := json.MarshalIndent(newLock, "", "  ")
-		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
-			return "", fmt.Errorf("failed to serialize new lockfile: %w", err)
-		}
+	// Persist the choice for future invocations
+	config.LockFileLocation = LockLocationRules
+	if useRoot {
+		config.LockFileLocation = LockLocationRoot
+	}
+	if err := config.Save(cursorDir); err != nil {
+		return "", err
+	}
 
-		err = os.WriteFile(newLockPath, data, 0o644)
-		if err != nil {
-			UseRootLockFile = oldUseRoot // Revert setting
-			return "", fmt.Errorf("failed to write new lockfile: %w", err)
+	if !oldLockExists && !newLockExists {
+		// Neither file exists, create a new empty lockfile
+		if err := newLockFile().Save(cursorDir); err != nil {
+			return "", err
 		}
 	}
 
-	// The setting change is now permanent
-	UseRootLockFile = useRoot
 	return newLockPath, nil
 }
 
@@ -196,10 +257,7 @@ func loadOrCreateLockFile(cursorDir string) (*LockFile, error) {
 		return nil, fmt.Errorf("failed to load lockfile: %w", err)
 	}
 	if lock == nil {
-		lock = &LockFile{
-			Installed: []string{},
-			Rules:     []RuleSource{},
-		}
+		lock = newLockFile()
 	}
 	return lock, nil
 }
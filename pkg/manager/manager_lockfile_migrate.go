@@ -0,0 +1,144 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// lockFileDocument is the on-disk shape shared by every lockfile version.
+// Fields that were dropped from LockFile are kept here so migrations can read them.
+type lockFileDocument struct {
+	Version int `json:"version"`
+
+	// Rule keys only, written by version 0 and 1 lockfiles
+	Installed []string `json:"installed,omitempty"`
+
+	Rules []RuleSource `json:"rules"`
+}
+
+// lockFileMigration upgrades a lockfile document from one schema version to the next.
+type lockFileMigration struct {
+	from        int
+	description string
+	migrate     func(cursorDir string, doc *lockFileDocument)
+}
+
+// lockFileMigrations is the chain of migrations, indexed by the version they upgrade from.
+//
+//   - version 0: unversioned lockfiles, either a bare "installed" list or "rules"
+//     kept in sync with "installed"
+//   - version 1: every installed key has a rule entry
+//   - version 2: "installed" is gone and local files are relative to .cursor/rules
+var lockFileMigrations = []lockFileMigration{
+	{
+		from:        0,
+		description: "create rule entries for keys that only exist in the installed list",
+		migrate:     migrateLockFileV0,
+	},
+	{
+		from:        1,
+		description: "store local files relative to .cursor/rules and drop the installed list",
+		migrate:     migrateLockFileV1,
+	},
+}
+
+// migrateLockFileDocument applies every migration needed to bring doc to CurrentLockFileVersion.
+func migrateLockFileDocument(cursorDir string, doc *lockFileDocument) error {
+	if doc.Version > CurrentLockFileVersion {
+		return fmt.Errorf("lockfile version %d is newer than this cursor-rules supports (%d), please upgrade cursor-rules",
+			doc.Version, CurrentLockFileVersion)
+	}
+
+	for _, m := range lockFileMigrations {
+		if doc.Version != m.from {
+			continue
+		}
+		Debugf("Migrating lockfile from version %d: %s", m.from, m.description)
+		m.migrate(cursorDir, doc)
+		doc.Version = m.from + 1
+	}
+
+	if doc.Version != CurrentLockFileVersion {
+		return fmt.Errorf("no migration path for lockfile version %d", doc.Version)
+	}
+	if doc.Rules == nil {
+		doc.Rules = []RuleSource{}
+	}
+	return nil
+}
+
+// migrateLockFileV0 adds rule entries for keys listed only in the legacy installed list.
+func migrateLockFileV0(_ string, doc *lockFileDocument) {
+	for _, key := range doc.Installed {
+		if containsRule(doc.Rules, key) {
+			continue
+		}
+		doc.Rules = append(doc.Rules, RuleSource{
+			Key:        key,
+			SourceType: SourceTypeBuiltIn,
+			Reference:  key,
+			LocalFiles: []string{key + ".mdc"},
+		})
+	}
+}
+
+// migrateLockFileV1 makes local files relative to .cursor/rules and drops the installed list.
+func migrateLockFileV1(cursorDir string, doc *lockFileDocument) {
+	for i := range doc.Rules {
+		doc.Rules[i].LocalFiles = relativeLocalFiles(cursorDir, doc.Rules[i])
+	}
+	doc.Installed = nil
+}
+
+// relativeLocalFiles returns the local files of a rule relative to .cursor/rules.
+// Absolute paths from another checkout are rebased on their .cursor/rules suffix.
+func relativeLocalFiles(cursorDir string, rule RuleSource) []string {
+	files := make([]string, 0, len(rule.LocalFiles))
+	for _, file := range rule.LocalFiles {
+		files = append(files, relativeRuleFile(cursorDir, rule.Key, file))
+	}
+	return files
+}
+
+// relativeRuleFile converts a single local file path to a .cursor/rules relative path.
+func relativeRuleFile(cursorDir, ruleKey, file string) string {
+	if !filepath.IsAbs(file) {
+		return filepath.ToSlash(file)
+	}
+
+	if rel, err := filepath.Rel(cursorDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+
+	// The lockfile may have been written in a different checkout of the project
+	marker := "/.cursor/rules/"
+	if idx := strings.LastIndex(filepath.ToSlash(file), marker); idx >= 0 {
+		return filepath.ToSlash(file)[idx+len(marker):]
+	}
+
+	return ruleKey + ".mdc"
+}
+
+// MigrateLockFile rewrites the lockfile on disk in the current schema.
+// It returns the version the lockfile had before migrating.
+func MigrateLockFile(cursorDir string) (int, error) {
+	doc, err := readLockFileDocument(cursorDir)
+	if err != nil {
+		return 0, err
+	}
+	if doc == nil {
+		return 0, fmt.Errorf("no lockfile found")
+	}
+
+	fromVersion := doc.Version
+	if err := migrateLockFileDocument(cursorDir, doc); err != nil {
+		return fromVersion, err
+	}
+
+	lock := &LockFile{Version: doc.Version, Rules: doc.Rules}
+	if err := lock.Save(cursorDir); err != nil {
+		return fromVersion, fmt.Errorf("failed to save migrated lockfile: %w", err)
+	}
+	return fromVersion, nil
+}
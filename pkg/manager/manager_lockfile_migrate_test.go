@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadLockFile_Migrations tests that older lockfile versions are migrated on load.
+func TestLoadLockFile_Migrations(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	lockPath := filepath.Join(cursorDir, LockFileName)
+
+	tests := []struct {
+		name          string
+		content       string
+		expectedRules []RuleSource
+		expectError   bool
+	}{
+		{
+			name:    "Installed list only",
+			content: `{"installed": ["python"]}`,
+			expectedRules: []RuleSource{
+				{Key: "python", SourceType: SourceTypeBuiltIn, Reference: "python", LocalFiles: []string{"python.mdc"}},
+			},
+		},
+		{
+			name: "Unversioned rules with absolute paths",
+			content: `{"installed": ["user/rule"], "rules": [{"key": "user/rule", "sourceType": "github-shorthand",
+				"reference": "user/rule", "localFiles": ["` + filepath.Join(cursorDir, "user", "rule.mdc") + `"]}]}`,
+			expectedRules: []RuleSource{
+				{Key: "user/rule", SourceType: SourceTypeGitHubShorthand, Reference: "user/rule",
+					LocalFiles: []string{"user/rule.mdc"}},
+			},
+		},
+		{
+			name: "Absolute paths from another checkout",
+			content: `{"rules": [{"key": "local/rel/rule", "sourceType": "local-rel", "reference": "rule.mdc",
+				"localFiles": ["/elsewhere/project/.cursor/rules/local/rel/rule.mdc"]}]}`,
+			expectedRules: []RuleSource{
+				{Key: "local/rel/rule", SourceType: SourceTypeLocalRel, Reference: "rule.mdc",
+					LocalFiles: []string{"local/rel/rule.mdc"}},
+			},
+		},
+		{
+			name:        "Newer version",
+			content:     `{"version": 99, "rules": []}`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(lockPath, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("Failed to write lockfile: %v", err)
+			}
+
+			lock, err := LoadLockFile(cursorDir)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadLockFile returned error: %v", err)
+			}
+
+			if lock.Version != CurrentLockFileVersion {
+				t.Errorf("Expected version %d, got %d", CurrentLockFileVersion, lock.Version)
+			}
+			if !reflect.DeepEqual(lock.Rules, tc.expectedRules) {
+				t.Errorf("Expected rules %+v, got %+v", tc.expectedRules, lock.Rules)
+			}
+		})
+	}
+}
+
+// TestMigrateLockFile tests that MigrateLockFile rewrites an old lockfile on disk.
+func TestMigrateLockFile(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	lockPath := filepath.Join(cursorDir, LockFileName)
+	if err := os.WriteFile(lockPath, []byte(`{"installed": ["python"]}`), 0o644); err != nil {
+		t.Fatalf("Failed to write lockfile: %v", err)
+	}
+
+	fromVersion, err := MigrateLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("MigrateLockFile returned error: %v", err)
+	}
+	if fromVersion != 0 {
+		t.Errorf("Expected to migrate from version 0, got %d", fromVersion)
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("Failed to read lockfile: %v", err)
+	}
+	if strings.Contains(string(data), `"installed"`) {
+		t.Errorf("Migrated lockfile still contains the installed list:\n%s", data)
+	}
+	if !strings.Contains(string(data), `"version": 2`) {
+		t.Errorf("Migrated lockfile has no version 2 marker:\n%s", data)
+	}
+}
@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Conflict markers written into a rule file when a merge can't be resolved automatically.
+const (
+	conflictMarkerLocal    = "<<<<<<< local"
+	conflictMarkerSep      = "======="
+	conflictMarkerUpstream = ">>>>>>> upstream"
+)
+
+// matchedLines maps each line of a to its position in b, or -1 if the line was changed.
+func matchedLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	for _, op := range diffLines(a, b) {
+		if op.kind == diffEqual {
+			matches[op.aIndex] = op.bIndex
+		}
+	}
+	return matches
+}
+
+// equalLines reports whether two line slices are identical.
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeConflict writes one conflicting chunk with markers around both sides.
+func writeConflict(sb *strings.Builder, ours, theirs []string) {
+	writeSide := func(lines []string) {
+		for _, line := range lines {
+			sb.WriteString(line)
+		}
+		// Markers must start on their own line
+		if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString(conflictMarkerLocal + "\n")
+	writeSide(ours)
+	sb.WriteString(conflictMarkerSep + "\n")
+	writeSide(theirs)
+	sb.WriteString(conflictMarkerUpstream + "\n")
+}
+
+// mergeThreeWay merges the changes from base to ours and from base to theirs line by line
+// (diff3). Regions changed on only one side, or identically on both, merge cleanly; regions
+// changed differently on both sides are written with conflict markers and reported.
+func mergeThreeWay(base, ours, theirs string) (string, bool) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchA, matchB := matchedLines(o, a), matchedLines(o, b)
+
+	var sb strings.Builder
+	conflict := false
+
+	// resolveChunk merges a region where at least one side differs from base
+	resolveChunk := func(oc, ac, bc []string) {
+		switch {
+		case equalLines(ac, oc), equalLines(ac, bc):
+			for _, line := range bc {
+				sb.WriteString(line)
+			}
+		case equalLines(bc, oc):
+			for _, line := range ac {
+				sb.WriteString(line)
+			}
+		default:
+			conflict = true
+			writeConflict(&sb, ac, bc)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for {
+		// Copy lines that are unchanged on both sides
+		for i < len(o) && matchA[i] == j && matchB[i] == k {
+			sb.WriteString(o[i])
+			i++
+			j++
+			k++
+		}
+
+		// The next base line kept by both sides ends the changed region
+		next := i
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+
+		if next == len(o) {
+			if i < len(o) || j < len(a) || k < len(b) {
+				resolveChunk(o[i:], a[j:], b[k:])
+			}
+			break
+		}
+
+		resolveChunk(o[i:next], a[j:matchA[next]], b[k:matchB[next]])
+		i, j, k = next, matchA[next], matchB[next]
+	}
+
+	return sb.String(), conflict
+}
+
+// hasConflictMarkers reports whether content still contains unresolved conflict markers.
+func hasConflictMarkers(content string) bool {
+	for _, line := range splitLines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == conflictMarkerLocal || line == conflictMarkerUpstream {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveRule marks the merge conflicts of a rule as resolved once no conflict markers
+// are left in its files.
+func ResolveRule(cursorDir, ruleKey string) error {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	rule, err := findRule(lock, ruleKey)
+	if err != nil {
+		return err
+	}
+
+	if !rule.MergeConflict {
+		return fmt.Errorf("rule %s has no unresolved merge conflicts", ruleKey)
+	}
+
+	for _, file := range rule.LocalFiles {
+		data, err := os.ReadFile(ruleFilePath(cursorDir, file))
+		if err != nil {
+			return &ErrLocalFileAccess{Path: file, Cause: err}
+		}
+		if hasConflictMarkers(string(data)) {
+			return fmt.Errorf("%s still contains conflict markers", file)
+		}
+	}
+
+	rule.MergeConflict = false
+	if err := lock.Save(cursorDir); err != nil {
+		return fmt.Errorf("failed to save lockfile: %w", err)
+	}
+	return nil
+}
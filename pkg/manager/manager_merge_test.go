@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMergeThreeWay tests line-based three-way merging.
+func TestMergeThreeWay(t *testing.T) {
+	tests := []struct {
+		name             string
+		base             string
+		ours             string
+		theirs           string
+		expected         string
+		expectedConflict bool
+	}{
+		{
+			name:     "only upstream changed",
+			base:     "a\nb\nc\n",
+			ours:     "a\nb\nc\n",
+			theirs:   "a\nB\nc\n",
+			expected: "a\nB\nc\n",
+		},
+		{
+			name:     "only local changed",
+			base:     "a\nb\nc\n",
+			ours:     "a\nb\nc\nlocal\n",
+			theirs:   "a\nb\nc\n",
+			expected: "a\nb\nc\nlocal\n",
+		},
+		{
+			name:     "separate changes",
+			base:     "a\nb\nc\nd\ne\n",
+			ours:     "local\nb\nc\nd\ne\n",
+			theirs:   "a\nb\nc\nd\nupstream\n",
+			expected: "local\nb\nc\nd\nupstream\n",
+		},
+		{
+			name:     "same change on both sides",
+			base:     "a\nb\n",
+			ours:     "a\nB\n",
+			theirs:   "a\nB\n",
+			expected: "a\nB\n",
+		},
+		{
+			name:             "conflicting changes",
+			base:             "a\nb\nc\n",
+			ours:             "a\nlocal\nc\n",
+			theirs:           "a\nupstream\nc\n",
+			expected:         "a\n<<<<<<< local\nlocal\n=======\nupstream\n>>>>>>> upstream\nc\n",
+			expectedConflict: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflict := mergeThreeWay(tt.base, tt.ours, tt.theirs)
+			if merged != tt.expected {
+				t.Errorf("Expected merge result %q, got %q", tt.expected, merged)
+			}
+			if conflict != tt.expectedConflict {
+				t.Errorf("Expected conflict=%v, got %v", tt.expectedConflict, conflict)
+			}
+		})
+	}
+}
+
+// TestApplyUpgradedContent tests that upgrades merge local edits and track conflicts until resolved.
+func TestApplyUpgradedContent(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	sourcePath := filepath.Join(tempDir, "rule.mdc")
+	if err := os.WriteFile(sourcePath, []byte("title\n\nbody\nfooter\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write test rule: %v", err)
+	}
+	if err := AddRuleByReference(cursorDir, sourcePath); err != nil {
+		t.Fatalf("AddRuleByReference returned error: %v", err)
+	}
+
+	ruleKey := generateRuleKey(sourcePath)
+	installedPath := filepath.Join(cursorDir, ruleKey+".mdc")
+	if err := os.WriteFile(installedPath, []byte("title\n\nlocal body\nfooter\n"), 0o644); err != nil {
+		t.Fatalf("Failed to edit installed rule: %v", err)
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("LoadLockFile returned error: %v", err)
+	}
+	rule, err := findRule(lock, ruleKey)
+	if err != nil {
+		t.Fatalf("findRule returned error: %v", err)
+	}
+
+	// A non-overlapping upstream change merges cleanly
+	upstream := []byte("new title\n\nbody\nfooter\n")
+	if err := applyUpgradedContent(context.Background(), cursorDir, rule, upstream); err != nil {
+		t.Fatalf("applyUpgradedContent returned error: %v", err)
+	}
+	data, err := os.ReadFile(installedPath)
+	if err != nil {
+		t.Fatalf("Failed to read merged rule: %v", err)
+	}
+	if string(data) != "new title\n\nlocal body\nfooter\n" {
+		t.Errorf("Unexpected merge result: %q", data)
+	}
+	if rule.MergeConflict || rule.ContentSHA256 != calculateSHA256(upstream) {
+		t.Errorf("Expected clean merge recording the upstream hash, got %+v", rule)
+	}
+
+	// An overlapping change leaves conflict markers
+	if err := applyUpgradedContent(context.Background(), cursorDir, rule, []byte("new title\n\nupstream body\nfooter\n")); err != nil {
+		t.Fatalf("applyUpgradedContent returned error: %v", err)
+	}
+	if !rule.MergeConflict {
+		t.Fatal("Expected merge conflict to be recorded")
+	}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	if err := ResolveRule(cursorDir, ruleKey); err == nil {
+		t.Error("Expected ResolveRule to fail while conflict markers remain")
+	}
+	if err := os.WriteFile(installedPath, []byte("new title\n\nresolved body\nfooter\n"), 0o644); err != nil {
+		t.Fatalf("Failed to resolve conflict: %v", err)
+	}
+	if err := ResolveRule(cursorDir, ruleKey); err != nil {
+		t.Fatalf("ResolveRule returned error: %v", err)
+	}
+
+	lock, err = LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("LoadLockFile returned error: %v", err)
+	}
+	if rule, _ := findRule(lock, ruleKey); rule.MergeConflict {
+		t.Error("Expected conflict to be cleared after resolving")
+	}
+}
@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// OfflineEnv enables offline mode like --offline when set to a true value ("1", "true").
+const OfflineEnv = "CURSOR_RULES_OFFLINE"
+
+// Offline makes every download come from the local cache instead of the network.
+// Anything not cached fails with ErrOfflineCacheMiss.
+var Offline bool
+
+// isOffline reports whether offline mode is enabled by --offline or CURSOR_RULES_OFFLINE.
+func isOffline() bool {
+	if Offline {
+		return true
+	}
+	enabled, err := strconv.ParseBool(os.Getenv(OfflineEnv))
+	return err == nil && enabled
+}
+
+// ruleNotFoundError reports that none of the URLs a reference could point to had the rule.
+// Offline, the rule may well exist upstream, so the error says it isn't cached instead.
+func ruleNotFoundError(ref, format string, args ...interface{}) error {
+	if isOffline() {
+		return &ErrOfflineCacheMiss{Item: ref}
+	}
+	return fmt.Errorf(format, args...)
+}
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setOffline enables offline mode for the duration of a test.
+func setOffline(t *testing.T) {
+	t.Helper()
+	old := Offline
+	Offline = true
+	t.Cleanup(func() {
+		Offline = old
+	})
+}
+
+// TestIsOffline tests enabling offline mode through the environment.
+func TestIsOffline(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"", false},
+		{"0", false},
+		{"false", false},
+		{"1", true},
+		{"true", true},
+		{"yes", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(OfflineEnv, tt.value)
+		if got := isOffline(); got != tt.expected {
+			t.Errorf("%s=%q: expected %v, got %v", OfflineEnv, tt.value, tt.expected, got)
+		}
+	}
+}
+
+// TestOfflineGitHubBlob tests installing from the cache offline and failing with
+// ErrOfflineCacheMiss for anything that was never downloaded.
+func TestOfflineGitHubBlob(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	const commit = "7777777777777777777777777777777777777777"
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/offline/branches/main", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"branch"`)
+		_, _ = w.Write([]byte(`{"commit":{"sha":"` + commit + `"}}`))
+	})
+	mux.HandleFunc("/owner/offline/main/rule.mdc", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("offline rule\n"))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	ref := "https://github.com/owner/offline/blob/main/rule.mdc"
+	if _, err := handleGitHubBlob(ctx, cursorDir, ref); err != nil {
+		t.Fatalf("handleGitHubBlob returned error online: %v", err)
+	}
+
+	setOffline(t)
+	requests = 0
+
+	rule, err := handleGitHubBlob(ctx, cursorDir, ref)
+	if err != nil {
+		t.Fatalf("handleGitHubBlob returned error offline: %v", err)
+	}
+	if rule.ResolvedCommit != commit {
+		t.Errorf("Expected resolved commit %s from the cache, got %s", commit, rule.ResolvedCommit)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests offline, got %d", requests)
+	}
+
+	_, err = handleGitHubBlob(ctx, cursorDir, "https://github.com/owner/offline/blob/main/other.mdc")
+	if !IsOfflineCacheMissError(err) {
+		t.Errorf("Expected ErrOfflineCacheMiss for an uncached file, got %v", err)
+	}
+
+	_, err = handleUsernameRule(ctx, cursorDir, "owner/uncached")
+	if !IsOfflineCacheMissError(err) {
+		t.Errorf("Expected ErrOfflineCacheMiss for an uncached shorthand reference, got %v", err)
+	}
+}
+
+// TestOfflineInstallFrozen tests that a frozen install restores GitHub rules offline from
+// the content cache.
+func TestOfflineInstallFrozen(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	content := []byte("frozen offline rule\n")
+	if _, err := cacheContent(content); err != nil {
+		t.Fatalf("cacheContent returned error: %v", err)
+	}
+
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:            "owner-repo-frozen",
+		SourceType:     SourceTypeGitHubFile,
+		Reference:      "https://github.com/owner/repo/blob/main/frozen.mdc",
+		SourceURL:      "https://github.com/owner/repo/blob/main/frozen.mdc",
+		GitRef:         "branch=main",
+		ResolvedCommit: "8888888888888888888888888888888888888888",
+		LocalFiles:     []string{"owner-repo-frozen.mdc"},
+		ContentSHA256:  calculateSHA256(content),
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	setOffline(t)
+	if _, err := InstallFrozen(context.Background(), cursorDir); err != nil {
+		t.Fatalf("InstallFrozen returned error offline: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cursorDir, "owner-repo-frozen.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read restored rule: %v", err)
+	}
+	if string(data) != string(content) {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+}
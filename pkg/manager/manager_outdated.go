@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OutdatedRule describes a rule whose upstream has moved since it was installed.
+type OutdatedRule struct {
+	RuleKey    string     `json:"ruleKey"`
+	SourceType SourceType `json:"sourceType"`
+
+	// Installed and available versions: "branch@commit", a tag, or "sha256:hash" for local files
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+}
+
+// OutdatedReport lists outdated rules and rules whose upstream couldn't be checked.
+type OutdatedReport struct {
+	Rules []OutdatedRule `json:"rules"`
+
+	// Rule keys whose upstream could not be checked, with the reason
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// shortHash shortens a content hash for display.
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// githubRuleLocation returns the host, owner, repo and path a GitHub rule was installed from.
+// For directory rules the path is the directory.
+func githubRuleLocation(rule RuleSource) (host *GitHubHost, owner, repo, path string, err error) {
+	sourceURL := rule.SourceURL
+	if sourceURL == "" && (rule.SourceType == SourceTypeGitHubFile || rule.SourceType == SourceTypeGitHubDir) {
+		sourceURL = rule.Reference
+	}
+
+	parse := parseGitHubBlobURL
+	if rule.SourceType == SourceTypeGitHubDir {
+		parse = parseGitHubTreeURL
+	}
+	host, owner, repo, _, path, ok := parse(sourceURL)
+	if !ok {
+		return nil, "", "", "", fmt.Errorf("no recorded GitHub source URL")
+	}
+	return host, owner, repo, path, nil
+}
+
+// checkRuleOutdated compares a rule with its upstream and returns a row if it has moved:
+// the branch head differs from ResolvedCommit (for a directory, only if its files changed),
+// a newer release tag exists (within the version range, if the rule has one), or the local
+// source file no longer matches ContentSHA256. Rules pinned to a commit, built-in and
+// unmanaged rules are never outdated.
+func checkRuleOutdated(ctx context.Context, cursorDir string, rule RuleSource) (*OutdatedRule, error) {
+	row := &OutdatedRule{RuleKey: rule.Key, SourceType: rule.SourceType}
+
+	switch rule.SourceType {
+	case SourceTypeGitHubFile, SourceTypeGitHubDir, SourceTypeGitHubShorthand, SourceTypeGitHubRepoPath:
+		switch {
+		case strings.HasPrefix(rule.GitRef, "branch="):
+			host, owner, repo, dir, err := githubRuleLocation(rule)
+			if err != nil {
+				return nil, err
+			}
+
+			branch := strings.TrimPrefix(rule.GitRef, "branch=")
+			latestCommit, err := getHeadCommitForBranch(ctx, host, owner, repo, branch)
+			if err != nil {
+				return nil, err
+			}
+			if latestCommit == rule.ResolvedCommit {
+				return nil, nil
+			}
+
+			// Compare directories like status --upstream does
+			if rule.SourceType == SourceTypeGitHubDir {
+				changed, err := dirChangedAtCommit(ctx, rule, host, owner, repo, dir, latestCommit)
+				if err != nil || !changed {
+					return nil, err
+				}
+			}
+
+			row.Current = branch
+			if rule.ResolvedCommit != "" {
+				row.Current += "@" + shortCommit(rule.ResolvedCommit)
+			}
+			row.Latest = branch + "@" + shortCommit(latestCommit)
+			return row, nil
+
+		case strings.HasPrefix(rule.GitRef, "tag=") && rule.VersionRange != "":
+			tag := strings.TrimPrefix(rule.GitRef, "tag=")
+			latest, err := latestTagInRange(ctx, rule)
+			if err != nil {
+				return nil, err
+			}
+			if latest == tag {
+				return nil, nil
+			}
+
+			row.Current, row.Latest = tag, latest
+			return row, nil
+
+		case strings.HasPrefix(rule.GitRef, "tag="):
+			tag := strings.TrimPrefix(rule.GitRef, "tag=")
+			if _, ok := parseSemver(tag); !ok {
+				return nil, nil
+			}
+
+			host, owner, repo, _, err := githubRuleLocation(rule)
+			if err != nil {
+				return nil, err
+			}
+
+			tags, err := listGitHubTags(ctx, host, owner, repo)
+			if err != nil {
+				return nil, err
+			}
+			latest := latestSemverTag(tags, tag)
+			if latest == "" {
+				return nil, nil
+			}
+
+			row.Current, row.Latest = tag, latest
+			return row, nil
+		}
+
+	case SourceTypeLocalAbs, SourceTypeLocalRel:
+		path := resolveLocalReference(cursorDir, rule.Reference)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, &ErrLocalFileAccess{Path: path, Cause: err}
+		}
+
+		latestHash := calculateSHA256(data)
+		if latestHash == rule.ContentSHA256 {
+			return nil, nil
+		}
+
+		row.Current = "sha256:" + shortHash(rule.ContentSHA256)
+		row.Latest = "sha256:" + shortHash(latestHash)
+		return row, nil
+	}
+
+	return nil, nil
+}
+
+// findOutdatedRules checks every rule in the lockfile against its upstream.
+func findOutdatedRules(ctx context.Context, cursorDir string, lock *LockFile) *OutdatedReport {
+	report := &OutdatedReport{Rules: []OutdatedRule{}}
+
+	for _, rule := range lock.Rules {
+		row, err := checkRuleOutdated(ctx, cursorDir, rule)
+		if err != nil {
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+			report.Errors[rule.Key] = err.Error()
+			continue
+		}
+		if row != nil {
+			report.Rules = append(report.Rules, *row)
+		}
+	}
+
+	return report
+}
+
+// FindOutdatedRules reports every rule whose upstream has moved since it was installed.
+func FindOutdatedRules(ctx context.Context, cursorDir string) (*OutdatedReport, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+	return findOutdatedRules(ctx, cursorDir, lock), nil
+}
@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindOutdatedRules tests that branch, tag and local rules are reported when their upstream moves.
+func TestFindOutdatedRules(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	const headCommit = "2222222222222222222222222222222222222222"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/branches/main", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commit":{"sha":"` + headCommit + `"}}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name":"v1.0.0"},{"name":"v1.2.0"},{"name":"v2.0.0-rc.1"}]`))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	localSource := filepath.Join(tempDir, "source.mdc")
+	if err := os.WriteFile(localSource, []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write local source: %v", err)
+	}
+
+	lock := &LockFile{Rules: []RuleSource{
+		{
+			Key:            "branch-behind",
+			SourceType:     SourceTypeGitHubFile,
+			Reference:      "https://github.com/owner/repo/blob/main/rules/a.mdc",
+			GitRef:         "branch=main",
+			ResolvedCommit: "1111111111111111111111111111111111111111",
+		},
+		{
+			Key:            "branch-current",
+			SourceType:     SourceTypeGitHubFile,
+			Reference:      "https://github.com/owner/repo/blob/main/rules/b.mdc",
+			GitRef:         "branch=main",
+			ResolvedCommit: headCommit,
+		},
+		{
+			Key:        "tag-behind",
+			SourceType: SourceTypeGitHubShorthand,
+			Reference:  "owner/repo/c@v1.0.0",
+			SourceURL:  "https://github.com/owner/repo/blob/v1.0.0/c.mdc",
+			GitRef:     "tag=v1.0.0",
+		},
+		{
+			Key:           "local-changed",
+			SourceType:    SourceTypeLocalAbs,
+			Reference:     localSource,
+			ContentSHA256: calculateSHA256([]byte("original\n")),
+		},
+		{
+			Key:        "pinned",
+			SourceType: SourceTypeGitHubFile,
+			Reference:  "https://github.com/owner/repo/blob/1111111111111111111111111111111111111111/d.mdc",
+			GitRef:     "commit=1111111111111111111111111111111111111111",
+		},
+		{
+			Key:        "no-source",
+			SourceType: SourceTypeGitHubShorthand,
+			Reference:  "owner/e",
+			GitRef:     "branch=main",
+		},
+	}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	report, err := FindOutdatedRules(ctx, cursorDir)
+	if err != nil {
+		t.Fatalf("FindOutdatedRules returned error: %v", err)
+	}
+
+	expected := map[string][2]string{
+		"branch-behind": {"main@1111111", "main@2222222"},
+		"tag-behind":    {"v1.0.0", "v1.2.0"},
+		"local-changed": {"sha256:" + shortHash(calculateSHA256([]byte("original\n"))),
+			"sha256:" + shortHash(calculateSHA256([]byte("changed\n")))},
+	}
+	if len(report.Rules) != len(expected) {
+		t.Fatalf("Expected %d outdated rules, got %+v", len(expected), report.Rules)
+	}
+	for _, row := range report.Rules {
+		want, ok := expected[row.RuleKey]
+		if !ok {
+			t.Errorf("Unexpected outdated rule %s", row.RuleKey)
+			continue
+		}
+		if row.Current != want[0] || row.Latest != want[1] {
+			t.Errorf("%s: expected %s -> %s, got %s -> %s", row.RuleKey, want[0], want[1], row.Current, row.Latest)
+		}
+	}
+
+	if _, ok := report.Errors["no-source"]; !ok || len(report.Errors) != 1 {
+		t.Errorf("Expected only no-source to fail the check, got %v", report.Errors)
+	}
+}
+
+// TestUpgradeAllRules tests that outdated rules are upgraded and recorded in one lockfile write.
+func TestUpgradeAllRules(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	oldContent, newContent := []byte("old\n"), []byte("new\n")
+
+	lock := &LockFile{}
+	for _, key := range []string{"first", "second"} {
+		source := filepath.Join(tempDir, key+".mdc")
+		if err := os.WriteFile(source, newContent, 0o644); err != nil {
+			t.Fatalf("Failed to write source: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(cursorDir, key+".mdc"), oldContent, 0o644); err != nil {
+			t.Fatalf("Failed to write rule: %v", err)
+		}
+		lock.Rules = append(lock.Rules, RuleSource{
+			Key:           key,
+			SourceType:    SourceTypeLocalAbs,
+			Reference:     source,
+			LocalFiles:    []string{key + ".mdc"},
+			ContentSHA256: calculateSHA256(oldContent),
+		})
+	}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	upgraded, err := UpgradeAllRules(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("UpgradeAllRules returned error: %v", err)
+	}
+	if len(upgraded) != 2 {
+		t.Errorf("Expected 2 upgraded rules, got %v", upgraded)
+	}
+
+	updated, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("LoadLockFile returned error: %v", err)
+	}
+	for _, rule := range updated.Rules {
+		if rule.ContentSHA256 != calculateSHA256(newContent) {
+			t.Errorf("%s: lockfile hash was not updated", rule.Key)
+		}
+		data, err := os.ReadFile(filepath.Join(cursorDir, rule.Key+".mdc"))
+		if err != nil {
+			t.Fatalf("Failed to read rule: %v", err)
+		}
+		if string(data) != string(newContent) {
+			t.Errorf("%s: expected upgraded content, got %q", rule.Key, data)
+		}
+	}
+
+	// Nothing is left to upgrade
+	upgraded, err = UpgradeAllRules(context.Background(), cursorDir)
+	if err != nil {
+		t.Fatalf("UpgradeAllRules returned error: %v", err)
+	}
+	if len(upgraded) != 0 {
+		t.Errorf("Expected no upgrades on the second run, got %v", upgraded)
+	}
+}
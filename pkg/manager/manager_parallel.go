@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultConcurrency is the number of rules downloaded at once unless configured otherwise.
+const DefaultConcurrency = 4
+
+// Concurrency bounds how many rules are downloaded at once when several are installed,
+// e.g. for a glob pattern or an add command with multiple references.
+var Concurrency = DefaultConcurrency
+
+// forEachParallel calls fn for every index in [0, n) on at most Concurrency goroutines and
+// waits for all calls to return. Callers store results by index, which keeps them in input
+// order no matter which download finishes first.
+func forEachParallel(n int, fn func(i int)) {
+	workers := Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// progressPrinter prints one line per finished item, like "[3/12] downloaded owner/rule".
+// Workers share one printer, so their lines never interleave.
+type progressPrinter struct {
+	mu    sync.Mutex
+	done  int
+	total int
+}
+
+// newProgressPrinter creates a printer for total items.
+func newProgressPrinter(total int) *progressPrinter {
+	return &progressPrinter{total: total}
+}
+
+// printf counts an item as finished and prints its progress line.
+func (p *progressPrinter) printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	fmt.Printf("[%d/%d] %s\n", p.done, p.total, fmt.Sprintf(format, args...))
+}
+
+// printItemf is printf for an item whose warnings were collected, see withItemWarnings.
+// The warnings follow the item's progress line.
+func (p *progressPrinter) printItemf(warnings *itemWarnings, format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	fmt.Printf("[%d/%d] %s\n", p.done, p.total, fmt.Sprintf(format, args...))
+	for _, line := range warnings.lines {
+		fmt.Printf("  Warning: %s\n", line)
+	}
+}
+
+// itemWarnings collects the warnings raised while one item of a parallel operation is
+// processed, so they are printed with the item instead of in the middle of other items.
+type itemWarnings struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+type itemWarningsKey struct{}
+
+// withItemWarnings returns a context in which warnf adds to the returned warnings instead
+// of printing.
+func withItemWarnings(ctx context.Context) (context.Context, *itemWarnings) {
+	warnings := &itemWarnings{}
+	return context.WithValue(ctx, itemWarningsKey{}, warnings), warnings
+}
+
+// warnf prints a warning, or collects it if ctx came from withItemWarnings.
+func warnf(ctx context.Context, format string, args ...interface{}) {
+	warnings, ok := ctx.Value(itemWarningsKey{}).(*itemWarnings)
+	if !ok {
+		fmt.Printf("Warning: "+format+"\n", args...)
+		return
+	}
+
+	warnings.mu.Lock()
+	defer warnings.mu.Unlock()
+	warnings.lines = append(warnings.lines, fmt.Sprintf(format, args...))
+}
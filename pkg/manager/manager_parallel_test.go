@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestForEachParallel tests that every index is processed and concurrency stays bounded.
+func TestForEachParallel(t *testing.T) {
+	oldConcurrency := Concurrency
+	defer func() {
+		Concurrency = oldConcurrency
+	}()
+
+	for _, concurrency := range []int{0, 1, 3, 50} {
+		Concurrency = concurrency
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		seen := make([]int, 20)
+		forEachParallel(len(seen), func(i int) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+			seen[i]++
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+
+		limit := concurrency
+		if limit < 1 {
+			limit = 1
+		}
+		if maxRunning > limit {
+			t.Errorf("Concurrency %d: %d calls ran at once", concurrency, maxRunning)
+		}
+		for i, count := range seen {
+			if count != 1 {
+				t.Errorf("Concurrency %d: index %d processed %d times", concurrency, i, count)
+			}
+		}
+	}
+}
+
+// TestAddRulesByReference tests adding several references with one lockfile write in
+// argument order, installing duplicates once and reporting every failure without stopping
+// at the first.
+func TestAddRulesByReference(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+	tempDir := getRootDirectory(cursorDir)
+
+	sourceDir := filepath.Join(tempDir, "sources")
+	if err := os.MkdirAll(sourceDir, 0o755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+
+	var refs []string
+	for i := 1; i <= 6; i++ {
+		path := filepath.Join(sourceDir, fmt.Sprintf("rule%d.mdc", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("rule %d\n", i)), 0o644); err != nil {
+			t.Fatalf("Failed to write source rule: %v", err)
+		}
+		refs = append(refs, path)
+	}
+	missing := []string{filepath.Join(sourceDir, "missing1.mdc"), filepath.Join(sourceDir, "missing2.mdc")}
+	refs = append([]string{refs[0], missing[0]}, append(refs[1:], missing[1])...)
+
+	// Duplicates are installed once
+	refs = append(refs, refs[0], refs[2])
+
+	err := AddRulesByReference(context.Background(), cursorDir, refs)
+	if err == nil {
+		t.Fatal("Expected an error for the missing references, got nil")
+	}
+	for _, ref := range missing {
+		if !strings.Contains(err.Error(), ref) {
+			t.Errorf("Expected error to mention %s, got: %v", ref, err)
+		}
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 6 {
+		t.Fatalf("Expected 6 rules, got %d", len(lock.Rules))
+	}
+	for i, rule := range lock.Rules {
+		expected := fmt.Sprintf("rule%d", i+1)
+		if !strings.HasSuffix(rule.Key, expected) {
+			t.Errorf("Rule %d: expected key ending in %s, got %s", i, expected, rule.Key)
+		}
+		if !fileExists(ruleFilePath(cursorDir, rule.LocalFiles[0])) {
+			t.Errorf("Rule %s: file %s is missing", rule.Key, rule.LocalFiles[0])
+		}
+	}
+}
+
+// TestWarnf tests that warnings are printed unless they are collected for an item.
+func TestWarnf(t *testing.T) {
+	ctx, warnings := withItemWarnings(context.Background())
+	forEachParallel(3, func(i int) {
+		warnf(ctx, "warning %d", i)
+	})
+	if len(warnings.lines) != 3 {
+		t.Errorf("Expected 3 collected warnings, got %v", warnings.lines)
+	}
+
+	other, otherWarnings := withItemWarnings(context.Background())
+	warnf(other, "other item")
+	if len(warnings.lines) != 3 || len(otherWarnings.lines) != 1 {
+		t.Errorf("Expected warnings to stay with their item, got %v and %v", warnings.lines, otherWarnings.lines)
+	}
+}
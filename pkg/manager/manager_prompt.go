@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// How upgrade treats rule files with local edits.
+const (
+	LocalChangesMerge     = "merge"     // Merge three-way with the upstream changes (default)
+	LocalChangesOverwrite = "overwrite" // Replace the edited file with the upstream version
+	LocalChangesKeep      = "keep"      // Leave the edited file alone and only record the new version
+)
+
+// PromptPolicy answers the questions upgrade and restore would otherwise ask on stdin,
+// so that scripted and CI runs behave deterministically.
+type PromptPolicy struct {
+	// Answer yes to every yes/no question (--yes)
+	AssumeYes bool
+
+	// Never read from stdin, even on a terminal (--no-input). Questions without an
+	// answer from another flag fail with ErrInputRequired.
+	NoInput bool
+
+	// How to handle local edits during an upgrade: "" behaves like LocalChangesMerge but
+	// asks before overwriting when no merge base is available (--on-local-changes)
+	OnLocalChanges string
+
+	// Unpin commit-pinned rules without asking (--unpin)
+	Unpin bool
+}
+
+// Prompts is the prompt policy used by every interactive question.
+var Prompts PromptPolicy
+
+// stdinIsTerminal reports whether stdin is an interactive terminal. It is a variable so
+// tests can pretend to be interactive.
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// canPrompt reports whether questions may be asked on stdin.
+func canPrompt() bool {
+	return !Prompts.NoInput && stdinIsTerminal()
+}
+
+// ValidateLocalChangesPolicy checks an --on-local-changes value.
+func ValidateLocalChangesPolicy(policy string) error {
+	switch policy {
+	case "", LocalChangesMerge, LocalChangesOverwrite, LocalChangesKeep:
+		return nil
+	default:
+		return fmt.Errorf("invalid local changes policy %q (expected %s, %s or %s)",
+			policy, LocalChangesOverwrite, LocalChangesKeep, LocalChangesMerge)
+	}
+}
+
+// readAnswer prints prompt and reads a one-word answer from stdin. If questions can't be
+// asked it fails with ErrInputRequired, naming hint as the way to answer without a prompt.
+func readAnswer(question, prompt, hint string) (string, error) {
+	if !canPrompt() {
+		return "", &ErrInputRequired{Question: question, Hint: hint}
+	}
+
+	fmt.Print(prompt)
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		// Empty input falls back to the question's default
+		return "", nil
+	}
+	return strings.ToLower(strings.TrimSpace(response)), nil
+}
+
+// promptYesNo asks a yes/no question on stdin. Anything but "y" or "yes" means no.
+// --yes answers it without asking; hint names the flag that answers it otherwise.
+func promptYesNo(question, hint string) (bool, error) {
+	if Prompts.AssumeYes {
+		fmt.Printf("%s yes (--yes)\n", question)
+		return true, nil
+	}
+
+	response, err := readAnswer(question, question+" (y/N): ", hint)
+	if err != nil {
+		return false, err
+	}
+	return response == "y" || response == "yes", nil
+}
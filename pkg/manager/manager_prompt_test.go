@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setPromptPolicy replaces the prompt policy and the terminal check for the duration of a test.
+func setPromptPolicy(t *testing.T, policy PromptPolicy, terminal bool) {
+	t.Helper()
+	oldPolicy, oldTerminal := Prompts, stdinIsTerminal
+	Prompts = policy
+	stdinIsTerminal = func() bool { return terminal }
+	t.Cleanup(func() {
+		Prompts, stdinIsTerminal = oldPolicy, oldTerminal
+	})
+}
+
+// TestPromptsWithoutTerminal tests that questions fail instead of defaulting when nobody can answer them.
+func TestPromptsWithoutTerminal(t *testing.T) {
+	setPromptPolicy(t, PromptPolicy{}, false)
+
+	if _, err := promptYesNo("Continue?", "use --yes"); !IsInputRequiredError(err) {
+		t.Errorf("Expected ErrInputRequired from promptYesNo, got %v", err)
+	}
+	if err := confirmUnpin("1111111111111111111111111111111111111111"); !IsInputRequiredError(err) {
+		t.Errorf("Expected ErrInputRequired from confirmUnpin, got %v", err)
+	}
+	if _, _, err := resolveConflict("rule", ""); !IsInputRequiredError(err) {
+		t.Errorf("Expected ErrInputRequired from resolveConflict, got %v", err)
+	}
+
+	// Flags answer the questions without a terminal
+	Prompts = PromptPolicy{AssumeYes: true}
+	if yes, err := promptYesNo("Continue?", "use --yes"); err != nil || !yes {
+		t.Errorf("Expected --yes to answer yes, got %v, %v", yes, err)
+	}
+
+	Prompts = PromptPolicy{Unpin: true}
+	if err := confirmUnpin("1111111111111111111111111111111111111111"); err != nil {
+		t.Errorf("Expected --unpin to unpin without asking, got %v", err)
+	}
+
+	// --no-input also applies on a terminal
+	setPromptPolicy(t, PromptPolicy{NoInput: true}, true)
+	if _, err := promptYesNo("Continue?", "use --yes"); !IsInputRequiredError(err) {
+		t.Errorf("Expected ErrInputRequired with --no-input, got %v", err)
+	}
+}
+
+// TestApplyUpgradedContentLocalChanges tests each --on-local-changes policy for an edited
+// rule whose installed version is not available as a merge base.
+func TestApplyUpgradedContentLocalChanges(t *testing.T) {
+	installed, edited, upstream := "installed\n", "edited\n", "upstream\n"
+
+	tests := []struct {
+		name            string
+		policy          PromptPolicy
+		expectErr       bool
+		expectedContent string
+	}{
+		{"overwrite", PromptPolicy{OnLocalChanges: LocalChangesOverwrite}, false, upstream},
+		{"keep", PromptPolicy{OnLocalChanges: LocalChangesKeep}, false, edited},
+		{"merge without base", PromptPolicy{OnLocalChanges: LocalChangesMerge}, true, edited},
+		{"ask without terminal", PromptPolicy{}, true, edited},
+		{"ask with --yes", PromptPolicy{AssumeYes: true}, false, upstream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPromptPolicy(t, tt.policy, false)
+
+			cursorDir := setupTestCursorDir(t)
+			path := filepath.Join(cursorDir, "rule.mdc")
+			if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+				t.Fatalf("Failed to write rule: %v", err)
+			}
+
+			// A built-in rule without a category can't be fetched again, so there is no base
+			rule := &RuleSource{
+				Key:           "rule",
+				SourceType:    SourceTypeBuiltIn,
+				LocalFiles:    []string{"rule.mdc"},
+				ContentSHA256: calculateSHA256([]byte(installed)),
+			}
+
+			err := applyUpgradedContent(context.Background(), cursorDir, rule, []byte(upstream))
+			if tt.expectErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectErr {
+				if err != nil {
+					t.Fatalf("applyUpgradedContent returned error: %v", err)
+				}
+				if rule.ContentSHA256 != calculateSHA256([]byte(upstream)) {
+					t.Error("Expected the lockfile hash to move to the upstream version")
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read rule: %v", err)
+			}
+			if string(data) != tt.expectedContent {
+				t.Errorf("Expected content %q, got %q", tt.expectedContent, data)
+			}
+		})
+	}
+}
+
+// TestRestoreFromSharedWithoutInput tests that a restore with conflicts and no way to resolve
+// them fails before any rule is written.
+func TestRestoreFromSharedWithoutInput(t *testing.T) {
+	setPromptPolicy(t, PromptPolicy{NoInput: true}, true)
+
+	cursorDir := setupTestCursorDir(t)
+	lock := &LockFile{Rules: []RuleSource{{
+		Key:        "existing",
+		SourceType: SourceTypeLocalRel,
+		Reference:  "./existing.mdc",
+		LocalFiles: []string{"existing.mdc"},
+	}}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	shareable := ShareableLock{
+		FormatVersion: 1,
+		Rules: []ShareableRule{
+			{Key: "new", SourceType: SourceTypeLocalRel, Reference: "./new.mdc", Content: "new\n", Filename: "new.mdc"},
+			{Key: "existing", SourceType: SourceTypeLocalRel, Reference: "./existing.mdc", Content: "shared\n", Filename: "existing.mdc"},
+		},
+	}
+	data, err := json.Marshal(shareable)
+	if err != nil {
+		t.Fatalf("Failed to marshal shareable: %v", err)
+	}
+	sharePath := filepath.Join(t.TempDir(), "share.json")
+	if err := os.WriteFile(sharePath, data, 0o644); err != nil {
+		t.Fatalf("Failed to write shareable: %v", err)
+	}
+
+	err = RestoreFromShared(context.Background(), cursorDir, sharePath, "")
+	if !IsInputRequiredError(err) {
+		t.Fatalf("Expected ErrInputRequired, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cursorDir, "new.mdc")); !os.IsNotExist(err) {
+		t.Errorf("Expected no rule to be written, got %v", err)
+	}
+	lock, err = LoadLockFile(cursorDir)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+	if len(lock.Rules) != 1 {
+		t.Errorf("Expected the lockfile to be unchanged, got %d rules", len(lock.Rules))
+	}
+
+	// --auto-resolve answers the question without input
+	if err := RestoreFromShared(context.Background(), cursorDir, sharePath, ActionSkip); err != nil {
+		t.Fatalf("RestoreFromShared with --auto-resolve=skip failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cursorDir, "new.mdc")); err != nil {
+		t.Errorf("Expected the non-conflicting rule to be restored: %v", err)
+	}
+}
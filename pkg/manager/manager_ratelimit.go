@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitWait is the longest a GitHub request waits for a rate limit to reset before
+// retrying. Zero fails immediately with ErrGitHubRateLimit.
+var RateLimitWait time.Duration
+
+// maxRateLimitRetries bounds how often one request is retried after waiting for a reset.
+const maxRateLimitRetries = 3
+
+// rateLimitSleep waits for d or until ctx is done. It is a variable so tests don't have to wait.
+var rateLimitSleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimitReset reports whether a response was rejected by a GitHub rate limit and when the
+// limit resets. Both the primary limit (X-RateLimit-Remaining: 0 with X-RateLimit-Reset) and
+// secondary limits (Retry-After) are recognized.
+func rateLimitReset(resp *http.Response, now time.Time) (time.Time, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return at, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0), true
+		}
+		// Limited without a reset time: GitHub resets the primary limit hourly at the latest
+		return now.Add(time.Hour), true
+	}
+
+	// A 429 is always a rate limit, even without headers
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return now.Add(time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// sendGitHubRequest sends a GitHub request. If GitHub rejects it because of a rate limit,
+// it waits for the reset and retries when the reset is within RateLimitWait, and otherwise
+// returns ErrGitHubRateLimit.
+func sendGitHubRequest(req *http.Request) (*http.Response, error) {
+	client, err := httpClientFrom(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		reset, limited := rateLimitReset(resp, time.Now())
+		if !limited {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		rateLimitErr := &ErrGitHubRateLimit{
+			Reference:     req.URL.String(),
+			ResetTime:     reset.Local().Format(time.RFC1123),
+			Reset:         reset,
+			Authenticated: req.Header.Get("Authorization") != "",
+		}
+
+		wait := time.Until(reset)
+		if wait < 0 {
+			wait = 0
+		}
+		if RateLimitWait == 0 || wait > RateLimitWait || attempt >= maxRateLimitRetries {
+			return nil, rateLimitErr
+		}
+
+		fmt.Printf("GitHub rate limit reached, waiting %s until %s before retrying...\n",
+			wait.Round(time.Second), reset.Local().Format(time.Kitchen))
+		if err := rateLimitSleep(req.Context(), wait); err != nil {
+			return nil, rateLimitErr
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRateLimitReset tests recognizing rate-limited responses and their reset time.
+func TestRateLimitReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name          string
+		status        int
+		headers       map[string]string
+		expectLimited bool
+		expectedReset time.Time
+	}{
+		{
+			name:          "primary limit",
+			status:        http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000600"},
+			expectLimited: true,
+			expectedReset: time.Unix(1700000600, 0),
+		},
+		{
+			name:          "secondary limit",
+			status:        http.StatusForbidden,
+			headers:       map[string]string{"Retry-After": "30"},
+			expectLimited: true,
+			expectedReset: now.Add(30 * time.Second),
+		},
+		{
+			name:          "too many requests",
+			status:        http.StatusTooManyRequests,
+			expectLimited: true,
+			expectedReset: now.Add(time.Minute),
+		},
+		{
+			name:   "forbidden without rate limit headers",
+			status: http.StatusForbidden,
+		},
+		{
+			name:    "successful response",
+			status:  http.StatusOK,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000600"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+
+			reset, limited := rateLimitReset(resp, now)
+			if limited != tt.expectLimited {
+				t.Fatalf("Expected limited=%v, got %v", tt.expectLimited, limited)
+			}
+			if limited && !reset.Equal(tt.expectedReset) {
+				t.Errorf("Expected reset at %v, got %v", tt.expectedReset, reset)
+			}
+		})
+	}
+}
+
+// TestDoGitHubRequestRateLimit tests failing with ErrGitHubRateLimit and retrying after the reset.
+func TestDoGitHubRequestRateLimit(t *testing.T) {
+	limitedResponses := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/branches/main", func(w http.ResponseWriter, r *http.Request) {
+		if limitedResponses > 0 {
+			limitedResponses--
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(5*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commit":{"sha":"abc"}}`))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	slept := 0
+	oldWait, oldSleep := RateLimitWait, rateLimitSleep
+	rateLimitSleep = func(ctx context.Context, d time.Duration) error {
+		slept++
+		return nil
+	}
+	defer func() {
+		RateLimitWait, rateLimitSleep = oldWait, oldSleep
+	}()
+
+	// Without a wait budget the typed error is returned right away
+	RateLimitWait = 0
+	limitedResponses = 1
+	_, err := getHeadCommitForBranch(ctx, githubDotCom, "owner", "repo", "main")
+	if !IsGitHubRateLimitError(err) {
+		t.Fatalf("Expected ErrGitHubRateLimit, got %v", err)
+	}
+	if slept != 0 {
+		t.Errorf("Expected no wait, waited %d times", slept)
+	}
+
+	// With a wait budget the request is retried after the reset
+	RateLimitWait = time.Minute
+	limitedResponses = 2
+	sha, err := getHeadCommitForBranch(ctx, githubDotCom, "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("getHeadCommitForBranch returned error: %v", err)
+	}
+	if sha != "abc" || slept != 2 {
+		t.Errorf("Expected sha abc after 2 waits, got %q after %d", sha, slept)
+	}
+
+	// Retries are bounded
+	limitedResponses = maxRateLimitRetries + 1
+	if _, err := getHeadCommitForBranch(ctx, githubDotCom, "owner", "repo", "main"); !IsGitHubRateLimitError(err) {
+		t.Errorf("Expected ErrGitHubRateLimit after %d retries, got %v", maxRateLimitRetries, err)
+	}
+}
+
+// TestInstallFirstGitHubBlob tests that candidate URLs are tried in order until one works and
+// that a rate limit ends the search.
+func TestInstallFirstGitHubBlob(t *testing.T) {
+	var tried []string
+	install := func(ctx context.Context, cursorDir, blobURL string) (RuleSource, error) {
+		tried = append(tried, blobURL)
+		switch blobURL {
+		case "found":
+			return RuleSource{Key: "found"}, nil
+		case "limited":
+			return RuleSource{}, &ErrGitHubRateLimit{Reference: blobURL}
+		default:
+			return RuleSource{}, errors.New("HTTP error 404")
+		}
+	}
+
+	tests := []struct {
+		urls          []string
+		expectedFound int
+		expectedTried int
+		expectedLimit bool
+	}{
+		{urls: []string{"missing", "found", "missing"}, expectedFound: 1, expectedTried: 2},
+		{urls: []string{"missing", "missing"}, expectedFound: -1, expectedTried: 2},
+		{urls: []string{"limited", "found"}, expectedFound: -1, expectedTried: 1, expectedLimit: true},
+	}
+
+	for _, tt := range tests {
+		tried = nil
+		rule, found, err := installFirstGitHubBlob(context.Background(), "", install, tt.urls...)
+		if IsGitHubRateLimitError(err) != tt.expectedLimit || (err != nil && !tt.expectedLimit) {
+			t.Errorf("%v: unexpected error %v", tt.urls, err)
+		}
+		if found != tt.expectedFound || len(tried) != tt.expectedTried {
+			t.Errorf("%v: expected index %d after %d tries, got %d after %d", tt.urls, tt.expectedFound,
+				tt.expectedTried, found, len(tried))
+		}
+		if found >= 0 && rule.Key != "found" {
+			t.Errorf("%v: expected the rule from the URL that worked, got %+v", tt.urls, rule)
+		}
+	}
+}
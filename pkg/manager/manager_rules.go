@@ -50,7 +50,7 @@ func addRuleImpl(cursorDir, category, ruleKey string) error {
 		return fmt.Errorf("failed preparing directory for rule '%s': %w", ruleKey, err)
 	}
 
-	err = os.WriteFile(targetPath, []byte(content), 0o644)
+	err = writeRuleFile(targetPath, []byte(content), 0o644)
 	if err != nil {
 		return fmt.Errorf("failed to write rule file: %w", err)
 	}
@@ -61,12 +61,12 @@ func addRuleImpl(cursorDir, category, ruleKey string) error {
 		SourceType: SourceTypeBuiltIn,
 		Reference:  ruleKey,
 		Category:   category,
-		LocalFiles: []string{targetPath},
+		LocalFiles: []string{ruleKey + ".mdc"},
+		// Calculate and store content hash for future modification checks
+		ContentSHA256: calculateSHA256([]byte(content)),
 	}
 
 	lock.Rules = append(lock.Rules, rule)
-	// For backwards compatibility
-	lock.Installed = append(lock.Installed, ruleKey)
 
 	err = lock.Save(cursorDir)
 	if err != nil {
@@ -76,17 +76,40 @@ func addRuleImpl(cursorDir, category, ruleKey string) error {
 	return nil
 }
 
+// getBuiltInTemplate returns the template content of a built-in rule. The template's key is
+// the rule's reference, which differs from the rule key for adopted files that were
+// renamed; older lockfiles only have the key.
+func getBuiltInTemplate(rule RuleSource) (string, error) {
+	if rule.Reference != "" && rule.Reference != rule.Key {
+		if content, err := templates.GetTemplate(rule.Category, rule.Reference); err == nil {
+			return content, nil
+		}
+	}
+	return templates.GetTemplate(rule.Category, rule.Key)
+}
+
 // AddRuleByReference installs a rule from a file or URL reference.
 func AddRuleByReference(cursorDir, ref string) error {
 	return AddRuleByReferenceFn(cursorDir, ref)
 }
 
+// AddRuleByReferenceContext is like AddRuleByReference, sending its requests with the
+// client set on ctx.
+func AddRuleByReferenceContext(ctx context.Context, cursorDir, ref string) error {
+	return addRuleByReference(ctx, cursorDir, ref)
+}
+
 // addRuleByReferenceImpl is the implementation of AddRuleByReference.
 // This function has been refactored to use the Strategy Pattern instead of a large
 // if/else chain. It now uses a registry of handlers to find the appropriate handler
 // for the given reference type, and delegates the processing to that handler.
 // The original goto statement has been replaced with proper function extraction.
 func addRuleByReferenceImpl(cursorDir, ref string) error {
+	return addRuleByReference(context.Background(), cursorDir, ref)
+}
+
+// addRuleByReference installs a rule from a reference with the handler registered for it.
+func addRuleByReference(ctx context.Context, cursorDir, ref string) error {
 	// Create a registry with all reference handlers
 	registry := NewReferenceHandlerRegistry()
 
@@ -103,7 +126,7 @@ func addRuleByReferenceImpl(cursorDir, ref string) error {
 	}
 
 	// Process the reference
-	rule, err := handler.Process(context.Background(), cursorDir, ref)
+	rule, err := handler.Process(ctx, cursorDir, ref)
 
 	// Handle specific error cases
 	if err != nil {
@@ -127,6 +150,107 @@ func addRuleByReferenceImpl(cursorDir, ref string) error {
 	return updateLockfileWithRule(cursorDir, rule)
 }
 
+// AddRulesByReference installs several references. Up to Concurrency of them are downloaded
+// at once, and the lockfile is written once at the end. Glob patterns and built-in templates
+// are installed afterwards one at a time, as they update the lockfile themselves, and so
+// are references sharing a rule key with an earlier one. Every reference is attempted; the
+// returned error covers all that failed.
+func AddRulesByReference(ctx context.Context, cursorDir string, refs []string) error {
+	registry := NewReferenceHandlerRegistry()
+
+	// A reference given twice is installed once
+	seen := make(map[string]bool, len(refs))
+	unique := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref] {
+			seen[ref] = true
+			unique = append(unique, ref)
+		}
+	}
+	refs = unique
+	progress := newProgressPrinter(len(refs))
+
+	// References that would be installed under the same key would write the same files at
+	// the same time. Only the first of them is downloaded in parallel, the others are added
+	// one at a time afterwards and find it in the lockfile.
+	sequential := make([]bool, len(refs))
+	keys := make(map[string]bool, len(refs))
+	for i, ref := range refs {
+		key := generateRuleKey(ref)
+		sequential[i] = keys[key]
+		keys[key] = true
+	}
+
+	// Resolve references in parallel; results stay in the order the references were given
+	rules := make([]RuleSource, len(refs))
+	errs := make([]error, len(refs))
+	forEachParallel(len(refs), func(i int) {
+		ref := refs[i]
+		handler := registry.FindHandler(ref)
+		if sequential[i] || handler == nil || isGlobPattern(ref) {
+			sequential[i] = true
+			return
+		}
+
+		ctx, warnings := withItemWarnings(ctx)
+		rule, err := handler.Process(ctx, cursorDir, ref)
+		var templateFoundErr *ErrTemplateFound
+		if errors.As(err, &templateFoundErr) {
+			sequential[i] = true
+			return
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to process reference: %w", err)
+			progress.printItemf(warnings, "failed %s: %v", ref, err)
+			return
+		}
+		rules[i] = rule
+		progress.printItemf(warnings, "downloaded %s", ref)
+	})
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+	added := false
+	for i := range refs {
+		if !sequential[i] && errs[i] == nil && addRuleToLock(lock, rules[i]) {
+			added = true
+		}
+	}
+	if added {
+		if err := lock.Save(cursorDir); err != nil {
+			return fmt.Errorf("failed to update lockfile: %w", err)
+		}
+	}
+
+	for i, ref := range refs {
+		if !sequential[i] {
+			continue
+		}
+		if err := AddRuleByReferenceContext(ctx, cursorDir, ref); err != nil {
+			errs[i] = err
+			progress.printf("failed %s: %v", ref, err)
+			continue
+		}
+		progress.printf("added %s", ref)
+	}
+
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("error adding rule from reference %q: %w", refs[i], err))
+		}
+	}
+	if len(failures) == 1 {
+		return failures[0]
+	}
+	if len(failures) > 1 {
+		return fmt.Errorf("%d of %d references failed:\n%w", len(failures), len(refs), errors.Join(failures...))
+	}
+	return nil
+}
+
 // RemoveRule uninstalls a rule and removes its files.
 func RemoveRule(cursorDir string, ruleKey string) error {
 	// Load the lockfile
@@ -147,59 +271,19 @@ func RemoveRule(cursorDir string, ruleKey string) error {
 		}
 	}
 
-	if ruleIndex == -1 {
-		// Legacy support - look for simple rule keys in the Installed list
-		for i, key := range lock.Installed {
-			if key == ruleKey {
-				// Old style, so we need to create a fake RuleSource
-				ruleIndex = i
-				rule = RuleSource{
-					Key:        ruleKey,
-					SourceType: SourceTypeBuiltIn,
-					Reference:  ruleKey,
-					LocalFiles: []string{filepath.Join(cursorDir, ruleKey+".mdc")},
-				}
-				break
-			}
-		}
-	}
-
 	if ruleIndex == -1 {
 		return &ErrRuleNotFound{RuleKey: ruleKey}
 	}
 
 	// Remove the rule files
 	for _, file := range rule.LocalFiles {
-		// Ensure the path is absolute for files that might be relative
-		filePath := file
-		if !filepath.IsAbs(file) {
-			filePath = filepath.Join(cursorDir, file)
-		}
-
-		// Remove the file if it exists
-		if fileExists(filePath) {
-			err = os.Remove(filePath)
-			if err != nil {
-				return &ErrLocalFileAccess{
-					Path:  filePath,
-					Cause: err,
-				}
-			}
+		if err := removeRuleFile(cursorDir, file); err != nil {
+			return err
 		}
 	}
 
 	// Remove from the lockfile
-	if ruleIndex < len(lock.Rules) {
-		lock.Rules = append(lock.Rules[:ruleIndex], lock.Rules[ruleIndex+1:]...)
-	}
-
-	// For backwards compatibility, remove from the Installed list too
-	for i, key := range lock.Installed {
-		if key == ruleKey {
-			lock.Installed = append(lock.Installed[:i], lock.Installed[i+1:]...)
-			break
-		}
-	}
+	lock.Rules = append(lock.Rules[:ruleIndex], lock.Rules[ruleIndex+1:]...)
 
 	// Save the lockfile
 	err = lock.Save(cursorDir)
@@ -210,6 +294,29 @@ func RemoveRule(cursorDir string, ruleKey string) error {
 	return nil
 }
 
+// removeRuleFile removes an installed rule file if it exists, along with the directories
+// below .cursor/rules that are left empty, e.g. those of a hierarchical key.
+func removeRuleFile(cursorDir, file string) error {
+	filePath := ruleFilePath(cursorDir, file)
+	if fileExists(filePath) {
+		if err := os.Remove(filePath); err != nil {
+			return &ErrLocalFileAccess{
+				Path:  filePath,
+				Cause: err,
+			}
+		}
+	}
+
+	// os.Remove fails on directories that still have files, which ends the walk
+	root := filepath.Clean(cursorDir)
+	for dir := filepath.Dir(filePath); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
 // ListInstalledRules returns the list of installed rules from the lockfile.
 func ListInstalledRules(cursorDir string) ([]string, error) {
 	lock, err := LoadLockFile(cursorDir)
@@ -217,17 +324,11 @@ func ListInstalledRules(cursorDir string) ([]string, error) {
 		return nil, err
 	}
 
-	// Prefer enhanced structure if available
-	if len(lock.Rules) > 0 {
-		var rules []string
-		for _, rule := range lock.Rules {
-			rules = append(rules, rule.Key)
-		}
-		return rules, nil
+	rules := make([]string, 0, len(lock.Rules))
+	for _, rule := range lock.Rules {
+		rules = append(rules, rule.Key)
 	}
-
-	// Fall back to legacy format
-	return lock.Installed, nil
+	return rules, nil
 }
 
 // GetInstalledRules returns the full RuleSource objects for installed rules.
@@ -238,87 +339,3 @@ func GetInstalledRules(cursorDir string) ([]RuleSource, error) {
 	}
 	return lock.Rules, nil
 }
-
-// SyncLocalRules scans the cursor/rules directory for .mdc files and updates the lockfile accordingly.
-func SyncLocalRules(cursorDir string) error {
-	// Load the lockfile
-	lock, err := LoadLockFile(cursorDir)
-	if err != nil {
-		return fmt.Errorf("failed to load lockfile: %w", err)
-	}
-
-	// Create a map of installed rules for quick lookup
-	installedRules := make(map[string]bool)
-	for _, rule := range lock.Rules {
-		installedRules[rule.Key] = true
-	}
-
-	// Scan the directory for .mdc files
-	foundRules := make(map[string]bool)
-	err = filepath.Walk(cursorDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Only process .mdc files
-		if !info.IsDir() && strings.HasSuffix(path, ".mdc") {
-			relativePath, err := filepath.Rel(cursorDir, path)
-			if err != nil {
-				return fmt.Errorf("failed to get relative path: %w", err)
-			}
-
-			// Extract the rule key from the filename
-			key := strings.TrimSuffix(relativePath, ".mdc")
-			foundRules[key] = true
-
-			// Add to lockfile if not already there
-			if !installedRules[key] {
-				rule := RuleSource{
-					Key:        key,
-					SourceType: SourceTypeBuiltIn, // Default to built-in for simplicity
-					Reference:  key,
-					LocalFiles: []string{path},
-				}
-				lock.Rules = append(lock.Rules, rule)
-				// For backwards compatibility
-				lock.Installed = append(lock.Installed, key)
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
-	}
-
-	// Check for rules in the lockfile that don't exist on disk
-	var updatedRules []RuleSource
-	for _, rule := range lock.Rules {
-		if foundRules[rule.Key] {
-			updatedRules = append(updatedRules, rule)
-		}
-	}
-
-	// Update the lockfile with the new list
-	if len(updatedRules) != len(lock.Rules) {
-		lock.Rules = updatedRules
-
-		// For backwards compatibility, update the Installed list too
-		var updatedInstalled []string
-		for _, key := range lock.Installed {
-			if foundRules[key] {
-				updatedInstalled = append(updatedInstalled, key)
-			}
-		}
-		lock.Installed = updatedInstalled
-
-		// Save the lockfile
-		err = lock.Save(cursorDir)
-		if err != nil {
-			return fmt.Errorf("failed to update lockfile: %w", err)
-		}
-	}
-
-	return nil
-}
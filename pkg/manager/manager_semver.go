@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version tag such as "v1.2.3".
+type semver struct {
+	major, minor, patch int
+
+	// Pre-release suffix without the leading "-" (e.g. "rc.1"), empty for releases
+	prerelease string
+}
+
+// parseSemver parses a tag like "v1.2.3", "1.2" or "v2.0.0-rc.1". Missing minor and patch
+// parts are treated as zero. Build metadata ("+...") is ignored.
+func parseSemver(tag string) (semver, bool) {
+	s := strings.TrimPrefix(tag, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v semver
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.prerelease = s[i+1:]
+		s = s[:i]
+		if v.prerelease == "" {
+			return semver{}, false
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return semver{}, false
+	}
+
+	nums := [3]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	v.major, v.minor, v.patch = nums[0], nums[1], nums[2]
+	return v, true
+}
+
+// compareSemver returns -1, 0 or 1 depending on whether a is lower than, equal to or
+// higher than b. A pre-release is lower than the release it precedes.
+func compareSemver(a, b semver) int {
+	for _, d := range [][2]int{{a.major, b.major}, {a.minor, b.minor}, {a.patch, b.patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	case a.prerelease < b.prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// latestSemverTag returns the highest release tag in tags that is newer than current,
+// or "" if there is none. Pre-releases and tags that aren't semantic versions are skipped.
+func latestSemverTag(tags []string, current string) string {
+	currentVersion, ok := parseSemver(current)
+	if !ok {
+		return ""
+	}
+
+	latest, latestTag := currentVersion, ""
+	for _, tag := range tags {
+		v, ok := parseSemver(tag)
+		if !ok || v.prerelease != "" {
+			continue
+		}
+		if compareSemver(v, latest) > 0 {
+			latest, latestTag = v, tag
+		}
+	}
+	return latestTag
+}
+
+// semverRange is a version constraint such as "^1.2" or "~1.4.0": every release from lower
+// (inclusive) up to upper (exclusive) matches.
+type semverRange struct {
+	lower, upper semver
+}
+
+// isSemverRange reports whether a tag is a caret or tilde constraint rather than a literal tag.
+func isSemverRange(tag string) bool {
+	return strings.HasPrefix(tag, "^") || strings.HasPrefix(tag, "~")
+}
+
+// parseSemverRange parses a caret or tilde constraint with npm semantics:
+//
+//	^1.2.3 := >=1.2.3 <2.0.0    ~1.4.0 := >=1.4.0 <1.5.0
+//	^0.2.3 := >=0.2.3 <0.3.0    ~1.4   := >=1.4.0 <1.5.0
+//	^0.0.3 := >=0.0.3 <0.0.4    ~1     := >=1.0.0 <2.0.0
+func parseSemverRange(constraint string) (semverRange, bool) {
+	if !isSemverRange(constraint) {
+		return semverRange{}, false
+	}
+	op, version := constraint[0], constraint[1:]
+
+	lower, ok := parseSemver(version)
+	if !ok || lower.prerelease != "" {
+		return semverRange{}, false
+	}
+	parts := len(strings.Split(strings.TrimPrefix(version, "v"), "."))
+
+	upper := semver{}
+	switch {
+	case op == '~' && parts == 1, op == '^' && (lower.major > 0 || parts == 1):
+		upper.major = lower.major + 1
+	case op == '~', lower.minor > 0 || parts == 2:
+		upper.major, upper.minor = lower.major, lower.minor+1
+	default:
+		upper.major, upper.minor, upper.patch = lower.major, lower.minor, lower.patch+1
+	}
+
+	return semverRange{lower: lower, upper: upper}, true
+}
+
+// contains reports whether a release version satisfies the range. Pre-releases never do.
+func (r semverRange) contains(v semver) bool {
+	return v.prerelease == "" && compareSemver(v, r.lower) >= 0 && compareSemver(v, r.upper) < 0
+}
+
+// highestMatchingTag returns the highest tag in tags that satisfies constraint, or "" if
+// none does.
+func highestMatchingTag(tags []string, constraint string) string {
+	r, ok := parseSemverRange(constraint)
+	if !ok {
+		return ""
+	}
+
+	var best semver
+	bestTag := ""
+	for _, tag := range tags {
+		v, ok := parseSemver(tag)
+		if !ok || !r.contains(v) {
+			continue
+		}
+		if bestTag == "" || compareSemver(v, best) > 0 {
+			best, bestTag = v, tag
+		}
+	}
+	return bestTag
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"testing"
+)
+
+// TestCompareSemver tests ordering of semantic version tags.
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v1.0.0", "v1.0.0", 0},
+		{"v1.0.0", "1.0", 0},
+		{"v1.2.0", "v1.10.0", -1},
+		{"v2.0.0", "v1.9.9", 1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v1.0.0-beta", "v1.0.0-alpha", 1},
+	}
+
+	for _, tt := range tests {
+		a, okA := parseSemver(tt.a)
+		b, okB := parseSemver(tt.b)
+		if !okA || !okB {
+			t.Fatalf("Failed to parse %s or %s", tt.a, tt.b)
+		}
+		if got := compareSemver(a, b); got != tt.expected {
+			t.Errorf("compareSemver(%s, %s) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{"main", "v1.x", "1.2.3.4", "v1.0.0-"} {
+		if _, ok := parseSemver(invalid); ok {
+			t.Errorf("Expected %q not to parse as a semantic version", invalid)
+		}
+	}
+}
+
+// TestLatestSemverTag tests picking the newest release tag.
+func TestLatestSemverTag(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0-rc.1", "latest", "v0.9.0"}
+
+	tests := []struct {
+		current  string
+		expected string
+	}{
+		{"v1.0.0", "v1.10.0"},
+		{"v1.10.0", ""},
+		{"main", ""},
+	}
+
+	for _, tt := range tests {
+		if got := latestSemverTag(tags, tt.current); got != tt.expected {
+			t.Errorf("latestSemverTag(%s) = %q, expected %q", tt.current, got, tt.expected)
+		}
+	}
+}
+
+// TestHighestMatchingTag tests resolving caret and tilde ranges against a tag list.
+func TestHighestMatchingTag(t *testing.T) {
+	tags := []string{"v0.2.3", "v0.2.9", "v0.3.0", "v1.2.0", "v1.4.0", "v1.4.7", "v1.5.0", "v2.0.0-rc.1", "v2.0.0", "latest"}
+
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{"^1.2", "v1.5.0"},
+		{"^1.2.0", "v1.5.0"},
+		{"~1.4.0", "v1.4.7"},
+		{"~1.4", "v1.4.7"},
+		{"~1", "v1.5.0"},
+		{"^0.2.3", "v0.2.9"},
+		{"^0.0.1", ""},
+		{"^2", "v2.0.0"},
+		{"^3", ""},
+		{"v1.2.0", ""},
+		{"^main", ""},
+	}
+
+	for _, tt := range tests {
+		if got := highestMatchingTag(tags, tt.constraint); got != tt.expected {
+			t.Errorf("highestMatchingTag(%s) = %q, expected %q", tt.constraint, got, tt.expected)
+		}
+	}
+}
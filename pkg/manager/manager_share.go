@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -100,7 +99,7 @@ func ShareRules(cursorDir string, shareFilePath string, embedContent bool) error
 			}
 			summary.BuiltIn[rule.Category] = append(summary.BuiltIn[rule.Category], rule.Key)
 
-		case SourceTypeGitHubFile:
+		case SourceTypeGitHubFile, SourceTypeGitHubDir:
 			// GitHub rules are easy to share
 			summary.GitHub = append(summary.GitHub, rule.Reference)
 
@@ -210,8 +209,18 @@ func loadShareableFromURL(ctx context.Context, url string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Share URLs may carry an access token, so unlike rule downloads they are never cached
+	if isOffline() {
+		return nil, &ErrOfflineCacheMiss{Item: redactSecrets(url)}
+	}
+
+	client, err := httpClientFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Send request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download shareable file: %w", err)
 	}
@@ -222,9 +231,12 @@ func loadShareableFromURL(ctx context.Context, url string) ([]byte, error) {
 		return nil, fmt.Errorf("HTTP error: %s", resp.Status)
 	}
 
-	// Read body
-	data, err := io.ReadAll(resp.Body)
+	// Read body, rejecting web pages and binary files
+	data, err := readTextDownload(resp, url, maxResponseSize)
 	if err != nil {
+		if IsInvalidDownloadError(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
@@ -260,7 +272,10 @@ func buildExistingRuleSet(lock *LockFile) map[string]bool {
 func resolveConflict(key, autoResolve string) (string, string, error) {
 	if autoResolve == "" {
 		// Prompt the user
-		action := promptForConflictResolution(key)
+		action, err := promptForConflictResolution(key)
+		if err != nil {
+			return "", "", err
+		}
 		if action == ActionSkip {
 			return "", "", ErrSkipRule
 		}
@@ -282,31 +297,32 @@ func resolveConflict(key, autoResolve string) (string, string, error) {
 }
 
 // promptForConflictResolution prompts the user to resolve a conflict.
-func promptForConflictResolution(key string) string {
-	fmt.Printf("Rule '%s' already exists. Choose an action:\n", key)
-	fmt.Println("  (s)kip - Skip this rule")
-	fmt.Println("  (o)verwrite - Replace the existing rule")
-	fmt.Println("  (r)ename - Install with a different name")
-	fmt.Print("Enter choice [s/o/r]: ")
-
-	var response string
-	_, err := fmt.Scanln(&response)
+func promptForConflictResolution(key string) (string, error) {
+	question := fmt.Sprintf("Rule '%s' already exists", key)
+	if canPrompt() {
+		fmt.Printf("%s. Choose an action:\n", question)
+		fmt.Println("  (s)kip - Skip this rule")
+		fmt.Println("  (o)verwrite - Replace the existing rule")
+		fmt.Println("  (r)ename - Install with a different name")
+	}
+
+	response, err := readAnswer(question, "Enter choice [s/o/r]: ",
+		"use --auto-resolve=skip, --auto-resolve=overwrite or --auto-resolve=rename")
 	if err != nil {
-		// Default to skip on error
-		fmt.Println("Invalid input, defaulting to skip")
-		return ActionSkip
+		return "", err
 	}
 
-	response = strings.ToLower(strings.TrimSpace(response))
 	switch response {
 	case "o", "overwrite":
-		return ActionOverwrite
+		return ActionOverwrite, nil
 	case "r", "rename":
-		return ActionRename
+		return ActionRename, nil
 	case "s", "skip":
-		fallthrough
+		return ActionSkip, nil
 	default:
-		return ActionSkip
+		// Default to skip on empty or unknown input
+		fmt.Println("Invalid input, defaulting to skip")
+		return ActionSkip, nil
 	}
 }
 
@@ -324,7 +340,7 @@ func processEmbeddedRuleContent(cursorDir string, sr *ShareableRule, key string)
 
 	// Write the file
 	filePath := filepath.Join(cursorDir, filename)
-	err := os.WriteFile(filePath, []byte(sr.Content), 0o644)
+	err := writeRuleFile(filePath, []byte(sr.Content), 0o644)
 	if err != nil {
 		return fmt.Errorf("failed to write rule file: %w", err)
 	}
@@ -334,8 +350,9 @@ func processEmbeddedRuleContent(cursorDir string, sr *ShareableRule, key string)
 		Key:        key,
 		SourceType: SourceTypeBuiltIn, // Simplify by treating as built-in
 		Reference:  key,
-		LocalFiles: []string{filePath},
-		// We could store ContentSHA256 here for future upgrade checks
+		LocalFiles: []string{filename},
+		// Calculate and store content hash for future modification checks
+		ContentSHA256: calculateSHA256([]byte(sr.Content)),
 	}
 
 	// Add to lockfile
@@ -349,7 +366,7 @@ func processEmbeddedRuleContent(cursorDir string, sr *ShareableRule, key string)
 		if r.Key == key {
 			// Remove any existing files
 			for _, file := range r.LocalFiles {
-				_ = os.Remove(file) // Ignore errors, file might not exist
+				_ = os.Remove(ruleFilePath(cursorDir, file)) // Ignore errors, file might not exist
 			}
 			// Remove from lockfile
 			lock.Rules = append(lock.Rules[:i], lock.Rules[i+1:]...)
@@ -359,8 +376,6 @@ func processEmbeddedRuleContent(cursorDir string, sr *ShareableRule, key string)
 
 	// Add the new rule
 	lock.Rules = append(lock.Rules, rule)
-	// For backwards compatibility
-	lock.Installed = append(lock.Installed, key)
 
 	// Save lockfile
 	err = lock.Save(cursorDir)
@@ -372,9 +387,9 @@ func processEmbeddedRuleContent(cursorDir string, sr *ShareableRule, key string)
 }
 
 // processGitHubRule processes a GitHub rule during restore.
-func processGitHubRule(cursorDir string, sr *ShareableRule, key string) error {
+func processGitHubRule(ctx context.Context, cursorDir string, sr *ShareableRule, key string) error {
 	// GitHub rules can be installed via AddRuleByReference
-	err := AddRuleByReference(cursorDir, sr.Reference)
+	err := AddRuleByReferenceContext(ctx, cursorDir, sr.Reference)
 	if err != nil {
 		// If it's already installed, that's not an error here
 		if strings.Contains(err.Error(), "already installed") {
@@ -405,7 +420,7 @@ func processLocalRule(cursorDir string, sr *ShareableRule) error {
 }
 
 // processRule processes a single rule during restore.
-func processRule(cursorDir string, sr ShareableRule, existingRules map[string]bool, autoResolve string) error {
+func processRule(ctx context.Context, cursorDir string, sr ShareableRule, existingRules map[string]bool, autoResolve string) error {
 	// Skip unshareable rules
 	if sr.Unshareable {
 		fmt.Printf("Skipping unshareable rule: %s\n", sr.Key)
@@ -440,7 +455,7 @@ func processRule(cursorDir string, sr ShareableRule, existingRules map[string]bo
 	case SourceTypeBuiltIn:
 		err = processBuiltInRule(cursorDir, &sr, key)
 	case SourceTypeGitHubFile, SourceTypeGitHubDir:
-		err = processGitHubRule(cursorDir, &sr, key)
+		err = processGitHubRule(ctx, cursorDir, &sr, key)
 	case SourceTypeLocalAbs, SourceTypeLocalRel:
 		err = processLocalRule(cursorDir, &sr)
 	default:
@@ -454,7 +469,9 @@ func processRule(cursorDir string, sr ShareableRule, existingRules map[string]bo
 	return nil
 }
 
-// RestoreFromShared restores rules from a shared file.
+// RestoreFromShared restores rules from a shared file. Conflicts that can't be resolved without
+// input fail the restore before anything is written; other failing rules don't stop the rest
+// but are returned as an error.
 func RestoreFromShared(ctx context.Context, cursorDir, sharePath, autoResolve string) error {
 	// Load and parse the shareable file
 	data, err := loadShareableData(ctx, sharePath)
@@ -476,15 +493,28 @@ func RestoreFromShared(ctx context.Context, cursorDir, sharePath, autoResolve st
 	// Build a set of existing rules for conflict detection
 	existingRules := buildExistingRuleSet(currentLock)
 
+	// Without a way to resolve conflicts, fail before anything is written
+	if autoResolve == "" && !canPrompt() {
+		for _, sr := range lock.Rules {
+			if !sr.Unshareable && existingRules[sr.Key] {
+				_, err := promptForConflictResolution(sr.Key)
+				return err
+			}
+		}
+	}
+
 	// Process each rule
 	processed := 0
-	skipped := 0
+	var errs []error
 
 	for _, sr := range lock.Rules {
-		err := processRule(cursorDir, sr, existingRules, autoResolve)
+		err := processRule(ctx, cursorDir, sr, existingRules, autoResolve)
 		if err != nil {
+			if IsInputRequiredError(err) {
+				return err
+			}
 			fmt.Printf("Error processing rule %s: %v\n", sr.Key, err)
-			skipped++
+			errs = append(errs, err)
 		} else {
 			processed++
 			// Add to existing rules map to handle duplicates in the shared file
@@ -492,6 +522,9 @@ func RestoreFromShared(ctx context.Context, cursorDir, sharePath, autoResolve st
 		}
 	}
 
-	fmt.Printf("Restored %d rules, skipped %d rules\n", processed, skipped)
+	fmt.Printf("Restored %d rules, failed %d rules\n", processed, len(errs))
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to restore %d rules: %w", len(errs), errors.Join(errs...))
+	}
 	return nil
 }
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StatusReport groups installed rules by how they compare to the lockfile,
+// similar to 'git status'. Producing it never modifies the lockfile.
+type StatusReport struct {
+	// Rule keys whose files all match the lockfile
+	Clean []string `json:"clean"`
+
+	// Rule keys with at least one file changed since it was installed
+	Modified []string `json:"modified"`
+
+	// Rule keys with at least one file missing from disk
+	Deleted []string `json:"deleted"`
+
+	// Rule keys with merge conflicts left by an upgrade that still need to be resolved
+	Unresolved []string `json:"unresolved"`
+
+	// .mdc files (relative to .cursor/rules) that no rule accounts for
+	Untracked []string `json:"untracked"`
+
+	// Rule keys whose upstream source has content different from the installed version
+	UpstreamChanged []string `json:"upstreamChanged,omitempty"`
+
+	// Rule keys whose upstream could not be checked, with the reason
+	UpstreamErrors map[string]string `json:"upstreamErrors,omitempty"`
+}
+
+// IsClean reports whether every rule matches the lockfile and no files are untracked.
+func (r *StatusReport) IsClean() bool {
+	return len(r.Modified) == 0 && len(r.Deleted) == 0 && len(r.Unresolved) == 0 && len(r.Untracked) == 0
+}
+
+// checkUpstreamChanged reports whether the upstream source of a rule now has
+// different content than what the lockfile records. Only rules that track a
+// branch can change upstream; rules pinned to a commit or tag never do.
+func checkUpstreamChanged(ctx context.Context, rule RuleSource) (bool, error) {
+	switch rule.SourceType {
+	case SourceTypeGitHubFile, SourceTypeGitHubShorthand, SourceTypeGitHubRepoPath:
+	case SourceTypeGitHubDir:
+		return checkUpstreamDirChanged(ctx, rule)
+	default:
+		return false, nil
+	}
+
+	if !strings.HasPrefix(rule.GitRef, "branch=") || rule.ContentSHA256 == "" {
+		return false, nil
+	}
+
+	host, owner, repo, path, err := githubRuleLocation(rule)
+	if err != nil {
+		return false, err
+	}
+
+	branch := strings.TrimPrefix(rule.GitRef, "branch=")
+	content, err := fetchGitHubRaw(ctx, host, owner, repo, branch, path)
+	if err != nil {
+		return false, err
+	}
+
+	return calculateSHA256(content) != rule.ContentSHA256, nil
+}
+
+// GetStatus compares .cursor/rules with the lockfile. If checkUpstream is true, rules
+// that track a GitHub branch are also compared with their upstream source; failures to
+// reach upstream are recorded in the report rather than returned.
+func GetStatus(ctx context.Context, cursorDir string, checkUpstream bool) (*StatusReport, error) {
+	verification, err := VerifyRules(cursorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	report := &StatusReport{
+		Clean:      []string{},
+		Modified:   []string{},
+		Deleted:    []string{},
+		Unresolved: []string{},
+		Untracked:  []string{},
+	}
+
+	// Deleted wins over modified when a rule has both kinds of files
+	states := make(map[string]FileStatus)
+	for _, f := range verification.Files {
+		switch f.Status {
+		case FileStatusUntracked:
+			report.Untracked = append(report.Untracked, f.Path)
+		case FileStatusMissing:
+			states[f.RuleKey] = FileStatusMissing
+		case FileStatusModified:
+			if states[f.RuleKey] != FileStatusMissing {
+				states[f.RuleKey] = FileStatusModified
+			}
+		}
+	}
+
+	for _, rule := range lock.Rules {
+		if rule.MergeConflict {
+			report.Unresolved = append(report.Unresolved, rule.Key)
+		}
+
+		switch states[rule.Key] {
+		case FileStatusMissing:
+			report.Deleted = append(report.Deleted, rule.Key)
+		case FileStatusModified:
+			report.Modified = append(report.Modified, rule.Key)
+		default:
+			report.Clean = append(report.Clean, rule.Key)
+		}
+
+		if !checkUpstream {
+			continue
+		}
+
+		changed, err := checkUpstreamChanged(ctx, rule)
+		if err != nil {
+			if report.UpstreamErrors == nil {
+				report.UpstreamErrors = make(map[string]string)
+			}
+			report.UpstreamErrors[rule.Key] = err.Error()
+			continue
+		}
+		if changed {
+			report.UpstreamChanged = append(report.UpstreamChanged, rule.Key)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestGetStatus tests that GetStatus groups rules without modifying the lockfile.
+func TestGetStatus(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	files := map[string]string{
+		"clean.mdc":    "clean",
+		"modified.mdc": "changed locally",
+		"stray.mdc":    "stray",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cursorDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	lock := &LockFile{Rules: []RuleSource{
+		{Key: "clean", SourceType: SourceTypeLocalAbs, LocalFiles: []string{"clean.mdc"}, ContentSHA256: calculateSHA256([]byte("clean"))},
+		{Key: "modified", SourceType: SourceTypeLocalAbs, LocalFiles: []string{"modified.mdc"}, ContentSHA256: calculateSHA256([]byte("original"))},
+		{Key: "deleted", SourceType: SourceTypeLocalAbs, LocalFiles: []string{"deleted.mdc"}, ContentSHA256: calculateSHA256([]byte("gone"))},
+	}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	lockPath, err := getLockFilePath(cursorDir)
+	if err != nil {
+		t.Fatalf("getLockFilePath returned error: %v", err)
+	}
+	before, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("Failed to read lockfile: %v", err)
+	}
+
+	report, err := GetStatus(context.Background(), cursorDir, true)
+	if err != nil {
+		t.Fatalf("GetStatus returned error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      []string
+		expected []string
+	}{
+		{"clean", report.Clean, []string{"clean"}},
+		{"modified", report.Modified, []string{"modified"}},
+		{"deleted", report.Deleted, []string{"deleted"}},
+		{"untracked", report.Untracked, []string{"stray.mdc"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.expected) {
+			t.Errorf("Expected %s to be %v, got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+	if report.IsClean() {
+		t.Error("Expected report not to be clean")
+	}
+
+	after, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("Failed to read lockfile: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Error("GetStatus modified the lockfile")
+	}
+}
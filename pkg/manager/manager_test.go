@@ -14,6 +14,20 @@ import (
 	"github.com/fireharp/cursor-rules/pkg/templates"
 )
 
+// TestMain keeps the content cache out of the user's home directory for all tests.
+func TestMain(m *testing.M) {
+	cacheDir, err := os.MkdirTemp("", "cursor-rules-cache-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create cache directory: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv(CacheDirEnv, cacheDir)
+
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
 // setupTestDir creates a temporary directory for testing.
 func setupTestDir(t *testing.T) string {
 	t.Helper()
@@ -32,6 +46,22 @@ func cleanupTestDir(t *testing.T, dir string) {
 	}
 }
 
+// setupTestCursorDir creates a project in a temporary directory that is removed when the
+// test ends, and returns its .cursor/rules directory.
+func setupTestCursorDir(t *testing.T) string {
+	t.Helper()
+	tempDir := setupTestDir(t)
+	t.Cleanup(func() {
+		cleanupTestDir(t, tempDir)
+	})
+
+	cursorDir := filepath.Join(tempDir, ".cursor", "rules")
+	if err := os.MkdirAll(cursorDir, 0o755); err != nil {
+		t.Fatalf("Failed to create cursor rules directory: %v", err)
+	}
+	return cursorDir
+}
+
 // setupTestTemplates creates mock templates for testing.
 func setupTestTemplates() {
 	// Initialize templates.Categories if it doesn't exist
@@ -84,8 +114,12 @@ func TestLoadLockFile_New(t *testing.T) {
 		t.Fatalf("LoadLockFile returned error: %v", err)
 	}
 
-	if len(lock.Installed) != 0 {
-		t.Errorf("Expected empty installed rules, got %d", len(lock.Installed))
+	if len(lock.Rules) != 0 {
+		t.Errorf("Expected empty installed rules, got %d", len(lock.Rules))
+	}
+
+	if lock.Version != CurrentLockFileVersion {
+		t.Errorf("Expected version %d, got %d", CurrentLockFileVersion, lock.Version)
 	}
 }
 
@@ -96,7 +130,10 @@ func TestLockFileSaveLoad(t *testing.T) {
 
 	// Create and save a lock file
 	lock := &LockFile{
-		Installed: []string{"test-rule", "python"},
+		Rules: []RuleSource{
+			{Key: "test-rule", SourceType: SourceTypeBuiltIn, LocalFiles: []string{"test-rule.mdc"}},
+			{Key: "python", SourceType: SourceTypeBuiltIn, LocalFiles: []string{"python.mdc"}},
+		},
 	}
 
 	if err := lock.Save(tempDir); err != nil {
@@ -120,8 +157,12 @@ func TestLockFileSaveLoad(t *testing.T) {
 		t.Fatalf("Failed to parse lock file JSON: %v", err)
 	}
 
-	if !reflect.DeepEqual(savedLock.Installed, lock.Installed) {
-		t.Errorf("Saved lock file contents don't match. Expected %v, got %v", lock.Installed, savedLock.Installed)
+	if !reflect.DeepEqual(savedLock.Rules, lock.Rules) {
+		t.Errorf("Saved lock file contents don't match. Expected %v, got %v", lock.Rules, savedLock.Rules)
+	}
+
+	if savedLock.Version != CurrentLockFileVersion {
+		t.Errorf("Expected saved version %d, got %d", CurrentLockFileVersion, savedLock.Version)
 	}
 
 	// Test loading the file
@@ -130,15 +171,15 @@ func TestLockFileSaveLoad(t *testing.T) {
 		t.Fatalf("LoadLockFile returned error: %v", err)
 	}
 
-	if !reflect.DeepEqual(loadedLock.Installed, lock.Installed) {
-		t.Errorf("Loaded lock file contents don't match. Expected %v, got %v", lock.Installed, loadedLock.Installed)
+	if !reflect.DeepEqual(loadedLock.Rules, lock.Rules) {
+		t.Errorf("Loaded lock file contents don't match. Expected %v, got %v", lock.Rules, loadedLock.Rules)
 	}
 }
 
 // TestIsInstalled tests the IsInstalled method.
 func TestIsInstalled(t *testing.T) {
 	lock := &LockFile{
-		Installed: []string{"test-rule", "python"},
+		Rules: []RuleSource{{Key: "test-rule"}, {Key: "python"}},
 	}
 
 	tests := []struct {
@@ -270,7 +311,6 @@ func TestUpgradeRule(t *testing.T) {
 
 	// Prepare a lockfile
 	lock := &LockFile{
-		Installed: []string{"test-rule"},
 		Rules: []RuleSource{
 			{
 				Key:        "test-rule",
@@ -890,9 +930,10 @@ func TestRestoreFromShared(t *testing.T) {
 		}
 
 		// Now call the actual RestoreFromShared function with "rename" auto-resolve
+		// The built-in rule fails, so the restore reports an error after the other rules
 		err := RestoreFromShared(context.Background(), cursorDir, shareFilePath, "rename")
-		if err != nil {
-			t.Fatalf("RestoreFromShared failed: %v", err)
+		if err == nil {
+			t.Fatalf("Expected RestoreFromShared to report the failed built-in rule")
 		}
 
 		// Check the lockfile to verify results
@@ -967,9 +1008,10 @@ func TestRestoreFromShared(t *testing.T) {
 		}
 
 		// Now call the actual RestoreFromShared function with "overwrite" auto-resolve
+		// The built-in rule fails, so the restore reports an error after the other rules
 		err := RestoreFromShared(context.Background(), cursorDir, shareFilePath, "overwrite")
-		if err != nil {
-			t.Fatalf("RestoreFromShared failed: %v", err)
+		if err == nil {
+			t.Fatalf("Expected RestoreFromShared to report the failed built-in rule")
 		}
 
 		// Check the lockfile to verify results
@@ -3,94 +3,143 @@ package manager
 import (
 	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
-
-	"github.com/fireharp/cursor-rules/pkg/templates"
 )
 
-// findRuleToUpgrade finds a rule in the lockfile by key.
-func findRuleToUpgrade(lock *LockFile, ruleKey string) (*RuleSource, error) {
+// findRule finds a rule in the lockfile by key.
+func findRule(lock *LockFile, ruleKey string) (*RuleSource, error) {
 	for i := range lock.Rules {
 		if lock.Rules[i].Key == ruleKey {
 			return &lock.Rules[i], nil
 		}
 	}
-	return nil, fmt.Errorf("rule not found: %s", ruleKey)
+	return nil, &ErrRuleNotFound{RuleKey: ruleKey}
 }
 
-// upgradeBuiltInRule upgrades a built-in rule.
-func upgradeBuiltInRule(cursorDir string, rule *RuleSource) error {
-	// Get the template content
-	content, err := templates.GetTemplate(rule.Category, rule.Key)
-	if err != nil {
-		return fmt.Errorf("failed to get template: %w", err)
-	}
+// applyUpgradedContent writes the upgraded content of a rule. Files without local edits are
+// replaced. Files with local edits are handled according to Prompts.OnLocalChanges: by
+// default they are merged three-way, using the content they were installed with as the
+// base, and if that isn't available the user is asked whether to overwrite the edits.
+func applyUpgradedContent(ctx context.Context, cursorDir string, rule *RuleSource, content []byte) error {
+	var base []byte
+	conflict := false
+
+	for _, file := range rule.LocalFiles {
+		path := ruleFilePath(cursorDir, file)
+		local, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return &ErrLocalFileAccess{Path: path, Cause: err}
+		}
 
-	// Write to each local file
-	for _, filePath := range rule.LocalFiles {
-		// Ensure we're using the full path, accounting for test files
-		fullPath := filePath
-		if !filepath.IsAbs(filePath) {
-			fullPath = filepath.Join(cursorDir, filePath)
+		edited := err == nil && rule.ContentSHA256 != "" && calculateSHA256(local) != rule.ContentSHA256
+		if edited && Prompts.OnLocalChanges == LocalChangesOverwrite {
+			fmt.Printf("Overwriting local edits in %s\n", file)
+			edited = false
+		}
+		if !edited {
+			if err := writeRuleFile(path, content, 0o644); err != nil {
+				return fmt.Errorf("failed to write rule file: %w", err)
+			}
+			continue
 		}
 
-		err = os.WriteFile(fullPath, []byte(content), 0o644)
-		if err != nil {
+		if Prompts.OnLocalChanges == LocalChangesKeep {
+			// The file stays as it is and now shows up as modified against the new version
+			fmt.Printf("Keeping local edits in %s\n", file)
+			if _, err := cacheContent(content); err != nil {
+				Debugf("applyUpgradedContent: could not cache content of %s: %v\n", file, err)
+			}
+			continue
+		}
+
+		if base == nil {
+			base, err = loadPristineContent(ctx, cursorDir, *rule)
+			if err != nil {
+				Debugf("applyUpgradedContent: no base for %s: %v\n", rule.Key, err)
+				if Prompts.OnLocalChanges == LocalChangesMerge {
+					return fmt.Errorf("can't merge local edits in %s without the installed version (%v), "+
+						"use --on-local-changes=overwrite or --on-local-changes=keep", file, err)
+				}
+				if err := promptForLocalModifications(path); err != nil {
+					return err
+				}
+				if err := writeRuleFile(path, content, 0o644); err != nil {
+					return fmt.Errorf("failed to write rule file: %w", err)
+				}
+				continue
+			}
+		}
+
+		merged, hasConflict := mergeThreeWay(string(base), string(local), string(content))
+		if hasConflict {
+			conflict = true
+			fmt.Printf("Conflicts while merging local edits in %s, resolve them and run 'cursor-rules resolve %s'\n",
+				file, rule.Key)
+		} else {
+			fmt.Printf("Merged local edits in %s\n", file)
+		}
+
+		// The upstream content is the new pristine version, the merge result is the local file
+		if _, err := cacheContent(content); err != nil {
+			Debugf("applyUpgradedContent: could not cache content of %s: %v\n", file, err)
+		}
+		if err := os.WriteFile(path, []byte(merged), 0o644); err != nil {
 			return fmt.Errorf("failed to write rule file: %w", err)
 		}
 	}
 
+	rule.ContentSHA256 = calculateSHA256(content)
+	rule.MergeConflict = conflict
 	return nil
 }
 
-// checkLocalModifications checks if a rule file has been modified locally.
-func checkLocalModifications(rule *RuleSource, cursorDir string) (bool, error) {
-	// Skip if no content hash
-	if rule.ContentSHA256 == "" {
-		return false, nil
-	}
-
-	// Check each file
-	for _, filePath := range rule.LocalFiles {
-		currentHash, err := fileContentSHA256(filePath)
-		if err != nil {
-			return false, fmt.Errorf("failed to calculate file hash: %w", err)
-		}
-
-		if currentHash != rule.ContentSHA256 {
-			return true, nil
-		}
+// upgradeBuiltInRule upgrades a built-in rule.
+func upgradeBuiltInRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	// Get the template content
+	content, err := getBuiltInTemplate(*rule)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
 	}
 
-	return false, nil
+	return applyUpgradedContent(ctx, cursorDir, rule, []byte(content))
 }
 
 // promptForLocalModifications prompts the user about local modifications.
 func promptForLocalModifications(filePath string) error {
 	fmt.Printf("Warning: Local modifications detected in %s\n", filePath)
-	fmt.Print("Do you want to overwrite your changes? (y/N): ")
-
-	var response string
-	_, err := fmt.Scanln(&response)
+	overwrite, err := promptYesNo("Do you want to overwrite your changes?",
+		"use --on-local-changes=overwrite or --on-local-changes=keep")
 	if err != nil {
-		// If there's an error (e.g. empty input), treat as "no"
+		return err
+	}
+	if !overwrite {
 		return fmt.Errorf("upgrade cancelled")
 	}
+	return nil
+}
 
-	response = strings.ToLower(strings.TrimSpace(response))
-	if response != "y" && response != "yes" {
-		return fmt.Errorf("upgrade cancelled")
+// confirmUnpin asks whether a rule pinned to a commit may move to the latest version.
+// --unpin and --yes answer it without asking.
+func confirmUnpin(sha string) error {
+	fmt.Printf("Rule is pinned to commit %s\n", shortCommit(sha))
+	if Prompts.Unpin {
+		return nil
 	}
 
+	unpin, err := promptYesNo("Do you want to unpin and use the latest version?", "use --unpin to unpin it")
+	if err != nil {
+		return err
+	}
+	if !unpin {
+		return fmt.Errorf("upgrade cancelled - rule remains pinned to %s", shortCommit(sha))
+	}
 	return nil
 }
 
 // upgradeGitHubBranchRule upgrades a GitHub rule that references a branch.
-func upgradeGitHubBranchRule(cursorDir string, rule *RuleSource, gitRef, owner, repo string) error {
+func upgradeGitHubBranchRule(ctx context.Context, cursorDir string, rule *RuleSource, gitRef string) error {
 	// Extract the branch name
 	parts := strings.Split(gitRef, "=")
 	if len(parts) != 2 || parts[0] != "branch" {
@@ -99,8 +148,18 @@ func upgradeGitHubBranchRule(cursorDir string, rule *RuleSource, gitRef, owner,
 
 	branch := parts[1]
 
+	// The host, repository and file path come from the blob URL the rule was installed from
+	sourceURL := rule.SourceURL
+	if sourceURL == "" {
+		sourceURL = rule.Reference
+	}
+	host, owner, repo, _, path, ok := parseGitHubBlobURL(sourceURL)
+	if !ok {
+		return fmt.Errorf("invalid GitHub URL: %s", sourceURL)
+	}
+
 	// Get the latest commit hash
-	latestCommit, err := getHeadCommitForBranch(context.Background(), owner, repo, branch)
+	latestCommit, err := getHeadCommitForBranch(ctx, host, owner, repo, branch)
 	if err != nil {
 		return fmt.Errorf("failed to get latest commit: %w", err)
 	}
@@ -111,142 +170,258 @@ func upgradeGitHubBranchRule(cursorDir string, rule *RuleSource, gitRef, owner,
 		return nil
 	}
 
-	// Handle local modifications if any
-	hasLocalMods, err := checkLocalModifications(rule, cursorDir)
+	// Download the file at the new commit
+	content, err := fetchGitHubRaw(ctx, host, owner, repo, latestCommit, path)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to download file: %w", err)
 	}
 
-	if hasLocalMods {
-		err = promptForLocalModifications(rule.LocalFiles[0])
-		if err != nil {
-			return err
-		}
+	// Write the new content, merging any local modifications
+	if err := applyUpgradedContent(ctx, cursorDir, rule, content); err != nil {
+		return err
 	}
 
-	// Create reference with the new commit
+	// Only move the rule to the new commit once its files were written
 	oldCommit := rule.ResolvedCommit
 	rule.ResolvedCommit = latestCommit
 
-	// Re-download the file
-	newURL := strings.Replace(rule.Reference, oldCommit, latestCommit, 1)
-
-	// For each local file, fetch and save the updated content
-	for _, filePath := range rule.LocalFiles {
-		resp, err := http.Get(newURL)
-		if err != nil {
-			return fmt.Errorf("failed to download file: %w", err)
-		}
-		defer resp.Body.Close()
-
-		content, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
-		}
-
-		err = os.WriteFile(filePath, content, 0o644)
-		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
-		}
-
-		// Update the content hash
-		rule.ContentSHA256 = calculateSHA256(content)
-	}
-
 	fmt.Printf("Updated from %s to %s on branch %s\n", shortCommit(oldCommit), shortCommit(latestCommit), branch)
 	return nil
 }
 
 // upgradeGitHubPinnedRule upgrades a GitHub rule that is pinned to a specific commit.
-func upgradeGitHubPinnedRule(cursorDir string, rule *RuleSource, gitRef string) error {
+func upgradeGitHubPinnedRule(ctx context.Context, cursorDir string, rule *RuleSource, gitRef string) error {
 	parts := strings.Split(gitRef, "=")
 	if len(parts) != 2 || parts[0] != "commit" {
 		return fmt.Errorf("invalid Git reference: %s", gitRef)
 	}
 
-	commitHash := parts[1]
-	fmt.Printf("Rule is pinned to commit %s\n", shortCommit(commitHash))
-	fmt.Print("Do you want to unpin and use the latest version? (y/N): ")
+	if err := confirmUnpin(parts[1]); err != nil {
+		return err
+	}
 
-	var response string
-	_, err := fmt.Scanln(&response)
+	// The rule follows the repository's default branch from now on
+	host, owner, repo, _, err := githubRuleLocation(*rule)
 	if err != nil {
-		// If there's an error (e.g. empty input), treat as "no"
-		return fmt.Errorf("upgrade cancelled")
+		return err
+	}
+	branch, err := getDefaultBranch(ctx, host, owner, repo)
+	if err != nil {
+		return fmt.Errorf("failed to get default branch: %w", err)
 	}
 
-	response = strings.ToLower(strings.TrimSpace(response))
-	if response != "y" && response != "yes" {
-		return fmt.Errorf("upgrade cancelled - rule remains pinned to %s", shortCommit(commitHash))
+	// Change from commit to branch reference once the upgrade succeeded
+	if err := upgradeGitHubBranchRule(ctx, cursorDir, rule, "branch="+branch); err != nil {
+		return err
 	}
+	rule.GitRef = "branch=" + branch
+	return nil
+}
 
-	// Extract owner, repo, and path from the reference
-	matches := githubBlobPattern.FindStringSubmatch(rule.Reference)
-	if len(matches) != 5 {
-		return fmt.Errorf("invalid GitHub URL: %s", rule.Reference)
+// resolveReferenceContent runs a reference through its handler in a scratch directory and
+// returns the resolved rule together with its content, leaving the project untouched.
+func resolveReferenceContent(ctx context.Context, ref string) (RuleSource, []byte, error) {
+	scratchDir, err := os.MkdirTemp("", "cursor-rules-resolve-*")
+	if err != nil {
+		return RuleSource{}, nil, fmt.Errorf("failed to create scratch directory: %w", err)
 	}
+	defer os.RemoveAll(scratchDir)
 
-	owner := matches[1]
-	repo := matches[2]
+	resolved, err := NewReferenceHandlerRegistry().Process(ctx, scratchDir, ref)
+	if err != nil {
+		return RuleSource{}, nil, err
+	}
+	if len(resolved.LocalFiles) != 1 {
+		return RuleSource{}, nil, fmt.Errorf("reference %s resolved to %d files, expected one", ref, len(resolved.LocalFiles))
+	}
 
-	// Change from commit to branch reference
-	rule.GitRef = "branch=main"
-	return upgradeGitHubBranchRule(cursorDir, rule, "branch=main", owner, repo)
+	content, err := os.ReadFile(ruleFilePath(scratchDir, resolved.LocalFiles[0]))
+	if err != nil {
+		return RuleSource{}, nil, fmt.Errorf("failed to read resolved rule: %w", err)
+	}
+	return resolved, content, nil
 }
 
-// upgradeLocalRule upgrades a local rule.
-func upgradeLocalRule(cursorDir string, rule *RuleSource) error {
-	// Local rules can't be upgraded automatically
-	fmt.Printf("Rule %s is from a local file source and cannot be upgraded automatically.\n", rule.Key)
-	fmt.Printf("Source: %s\n", rule.Reference)
+// upgradeFromReference re-resolves a rule through ref and installs the result in place,
+// keeping the rule's key and files. ref may differ from the rule's current reference,
+// e.g. when moving to a newer tag.
+func upgradeFromReference(ctx context.Context, cursorDir string, rule *RuleSource, ref string) error {
+	resolved, content, err := resolveReferenceContent(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	if ref == rule.Reference && calculateSHA256(content) == rule.ContentSHA256 {
+		fmt.Printf("Rule %s is already up to date\n", rule.Key)
+	} else if err := applyUpgradedContent(ctx, cursorDir, rule, content); err != nil {
+		return err
+	}
+
+	// Record where the reference resolved to, even if the content didn't change
+
+	rule.SourceType = resolved.SourceType
+	rule.Reference = ref
+	rule.GitRef = resolved.GitRef
+	rule.VersionRange = resolved.VersionRange
+	rule.ResolvedCommit = resolved.ResolvedCommit
+	rule.SourceURL = resolved.SourceURL
 	return nil
 }
 
-// UpgradeRule upgrades a rule to the latest version.
-func UpgradeRule(cursorDir, ruleKey string) error {
-	// Load the lockfile
-	lock, err := LoadLockFile(cursorDir)
+// upgradeGitHubTagRule moves a username/rule@tag rule to the newest release tag, or a
+// username/rule@^1.2 rule to the newest tag within its range.
+func upgradeGitHubTagRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	username, ruleName, tag, ok := parseUsernameRuleWithTag(rule.Reference)
+	if !ok {
+		return fmt.Errorf("invalid username/rule@tag reference: %s", rule.Reference)
+	}
+
+	if rule.VersionRange != "" {
+		return upgradeGitHubRangeRule(ctx, cursorDir, rule)
+	}
+
+	if _, ok := parseSemver(tag); !ok {
+		return fmt.Errorf("tag %s is not a semantic version, newer tags can't be determined", tag)
+	}
+
+	host, owner, repo := shorthandGitHubHost(), username, "cursor-rules-collection"
+	if urlHost, urlOwner, urlRepo, _, _, ok := parseGitHubBlobURL(rule.SourceURL); ok {
+		host, owner, repo = urlHost, urlOwner, urlRepo
+	}
+
+	tags, err := listGitHubTags(ctx, host, owner, repo)
 	if err != nil {
-		return fmt.Errorf("failed to load lockfile: %w", err)
+		return fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
 	}
 
-	// Find the rule
-	rule, err := findRuleToUpgrade(lock, ruleKey)
+	latest := latestSemverTag(tags, tag)
+	if latest == "" {
+		fmt.Printf("Rule is already at the latest tag (%s)\n", tag)
+		return nil
+	}
+
+	fmt.Printf("Upgrading from tag %s to %s\n", tag, latest)
+	return upgradeFromReference(ctx, cursorDir, rule, fmt.Sprintf("%s/%s@%s", username, ruleName, latest))
+}
+
+// latestTagInRange returns the highest tag of the rule's repository within its version range.
+func latestTagInRange(ctx context.Context, rule RuleSource) (string, error) {
+	host, owner, repo, _, err := githubRuleLocation(rule)
+	if err != nil {
+		return "", err
+	}
+
+	tags, err := listGitHubTags(ctx, host, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+	}
+
+	latest := highestMatchingTag(tags, rule.VersionRange)
+	if latest == "" {
+		return "", fmt.Errorf("no tag of %s/%s matches %s", owner, repo, rule.VersionRange)
+	}
+	return latest, nil
+}
+
+// upgradeGitHubRangeRule moves a rule with a version range to the highest tag within the
+// range. The reference keeps the range, so the key and reference stay the same.
+func upgradeGitHubRangeRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	tag := strings.TrimPrefix(rule.GitRef, "tag=")
+
+	latest, err := latestTagInRange(ctx, *rule)
 	if err != nil {
 		return err
 	}
+	if latest == tag {
+		fmt.Printf("Rule is already at the latest tag within %s (%s)\n", rule.VersionRange, tag)
+		return nil
+	}
+
+	fmt.Printf("Upgrading from tag %s to %s (within %s)\n", tag, latest, rule.VersionRange)
+	return upgradeFromReference(ctx, cursorDir, rule, rule.Reference)
+}
+
+// upgradeGitHubShorthandRule upgrades a rule added with a username/rule style reference by
+// resolving it again. Tag-pinned rules move to the newest tag; commit-pinned rules are only
+// unpinned after confirmation.
+func upgradeGitHubShorthandRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	switch {
+	case strings.HasPrefix(rule.GitRef, "tag="):
+		return upgradeGitHubTagRule(ctx, cursorDir, rule)
+
+	case strings.HasPrefix(rule.GitRef, "commit="):
+		username, ruleName, sha, ok := parseUsernameRuleWithSha(rule.Reference)
+		if !ok {
+			return fmt.Errorf("invalid username/rule:sha reference: %s", rule.Reference)
+		}
+
+		if err := confirmUnpin(sha); err != nil {
+			return err
+		}
+		return upgradeFromReference(ctx, cursorDir, rule, username+"/"+ruleName)
+
+	default:
+		return upgradeFromReference(ctx, cursorDir, rule, rule.Reference)
+	}
+}
+
+// upgradeLocalRule upgrades a local rule by copying its source file again.
+func upgradeLocalRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	path := resolveLocalReference(cursorDir, rule.Reference)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return &ErrLocalFileAccess{Path: path, Cause: err}
+	}
+
+	if calculateSHA256(content) == rule.ContentSHA256 {
+		fmt.Printf("Rule %s is already up to date with %s\n", rule.Key, rule.Reference)
+		return nil
+	}
+
+	return applyUpgradedContent(ctx, cursorDir, rule, content)
+}
+
+// upgradeRule upgrades a single rule of a loaded lockfile in place. The caller saves the lockfile.
+func upgradeRule(ctx context.Context, cursorDir string, rule *RuleSource) error {
+	// Merging again on top of conflict markers would only make things worse
+	if rule.MergeConflict {
+		return fmt.Errorf("rule %s has unresolved merge conflicts, fix them and run 'cursor-rules resolve %s' first",
+			rule.Key, rule.Key)
+	}
+
+	var err error
 
 	// Handle different source types
 	switch rule.SourceType {
 	case SourceTypeBuiltIn:
 		// For built-in rules, just reinstall from the template
 		fmt.Printf("Upgrading built-in rule: %s\n", rule.Key)
-		err = upgradeBuiltInRule(cursorDir, rule)
+		err = upgradeBuiltInRule(ctx, cursorDir, rule)
 
 	case SourceTypeGitHubFile:
 		// For GitHub rules, check if it's a branch or pinned commit
 		if strings.HasPrefix(rule.GitRef, "branch=") {
-			// Get owner and repo from reference
-			matches := githubBlobPattern.FindStringSubmatch(rule.Reference)
-			if len(matches) != 5 {
-				return fmt.Errorf("invalid GitHub URL: %s", rule.Reference)
-			}
-
-			owner := matches[1]
-			repo := matches[2]
-
 			fmt.Printf("Upgrading GitHub rule from branch: %s\n", strings.Split(rule.GitRef, "=")[1])
-			err = upgradeGitHubBranchRule(cursorDir, rule, rule.GitRef, owner, repo)
+			err = upgradeGitHubBranchRule(ctx, cursorDir, rule, rule.GitRef)
 		} else if strings.HasPrefix(rule.GitRef, "commit=") {
 			fmt.Printf("Upgrading GitHub rule from pinned commit\n")
-			err = upgradeGitHubPinnedRule(cursorDir, rule, rule.GitRef)
+			err = upgradeGitHubPinnedRule(ctx, cursorDir, rule, rule.GitRef)
 		} else {
 			return fmt.Errorf("unknown Git reference type: %s", rule.GitRef)
 		}
 
+	case SourceTypeGitHubDir:
+		fmt.Printf("Upgrading GitHub directory: %s\n", rule.Reference)
+		err = upgradeGitHubDirRule(ctx, cursorDir, rule)
+
+	case SourceTypeGitHubShorthand, SourceTypeGitHubRepoPath:
+		// Shorthand references are resolved again through their original reference
+		fmt.Printf("Upgrading GitHub rule: %s\n", rule.Reference)
+		err = upgradeGitHubShorthandRule(ctx, cursorDir, rule)
+
 	case SourceTypeLocalAbs, SourceTypeLocalRel:
-		// Local files can't be auto-upgraded
-		err = upgradeLocalRule(cursorDir, rule)
+		fmt.Printf("Upgrading local rule from: %s\n", rule.Reference)
+		err = upgradeLocalRule(ctx, cursorDir, rule)
 
 	default:
 		return fmt.Errorf("unsupported source type for upgrade: %s", rule.SourceType)
@@ -255,6 +430,31 @@ func UpgradeRule(cursorDir, ruleKey string) error {
 	if err != nil {
 		return fmt.Errorf("upgrade failed: %w", err)
 	}
+	return nil
+}
+
+// UpgradeRule upgrades a rule to the latest version.
+func UpgradeRule(cursorDir, ruleKey string) error {
+	return UpgradeRuleContext(context.Background(), cursorDir, ruleKey)
+}
+
+// UpgradeRuleContext is like UpgradeRule, sending its requests with the client set on ctx.
+func UpgradeRuleContext(ctx context.Context, cursorDir, ruleKey string) error {
+	// Load the lockfile
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	// Find the rule
+	rule, err := findRule(lock, ruleKey)
+	if err != nil {
+		return err
+	}
+
+	if err := upgradeRule(ctx, cursorDir, rule); err != nil {
+		return err
+	}
 
 	// Save the lockfile with any changes (like updated commit hashes)
 	err = lock.Save(cursorDir)
@@ -265,3 +465,49 @@ func UpgradeRule(cursorDir, ruleKey string) error {
 	fmt.Printf("Rule %s upgraded successfully\n", rule.Key)
 	return nil
 }
+
+// UpgradeAllRules upgrades every rule reported by FindOutdatedRules and writes the lockfile
+// once at the end. A failing rule doesn't stop the others; the keys of the rules that were
+// upgraded are returned together with an error listing the failures.
+func UpgradeAllRules(ctx context.Context, cursorDir string) ([]string, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	report := findOutdatedRules(ctx, cursorDir, lock)
+	failedKeys := make([]string, 0, len(report.Errors))
+	for key := range report.Errors {
+		failedKeys = append(failedKeys, key)
+	}
+	sort.Strings(failedKeys)
+	for _, key := range failedKeys {
+		fmt.Printf("Warning: could not check %s for updates: %s\n", key, report.Errors[key])
+	}
+
+	var upgraded, failures []string
+	for _, outdated := range report.Rules {
+		rule, err := findRule(lock, outdated.RuleKey)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Printf("Upgrading %s from %s to %s\n", rule.Key, outdated.Current, outdated.Latest)
+		if err := upgradeRule(ctx, cursorDir, rule); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", rule.Key, err))
+			continue
+		}
+		upgraded = append(upgraded, rule.Key)
+	}
+
+	if len(upgraded) > 0 {
+		if err := lock.Save(cursorDir); err != nil {
+			return nil, fmt.Errorf("failed to save lockfile: %w", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return upgraded, fmt.Errorf("%d rule(s) failed to upgrade:\n  %s", len(failures), strings.Join(failures, "\n  "))
+	}
+	return upgraded, nil
+}
@@ -0,0 +1,247 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// startTestGitHubServer serves both the GitHub API and raw content from handler for the
+// duration of the test. Requests sent with the returned context go to the test server
+// instead of github.com.
+func startTestGitHubServer(t *testing.T, handler http.Handler) context.Context {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// Responses cached from an earlier test server would answer for this one
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatalf("Failed to get cache dir: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(cacheDir, "http")); err != nil {
+		t.Fatalf("Failed to clear HTTP cache: %v", err)
+	}
+
+	client, err := newHTTPClient(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	githubHosts := map[string]bool{}
+	for _, hostURL := range []string{githubDotCom.APIURL, githubDotCom.RawURL} {
+		if u, err := url.Parse(hostURL); err == nil {
+			githubHosts[u.Host] = true
+		}
+	}
+
+	transport := client.client.Transport
+	client.client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if githubHosts[req.URL.Host] {
+			req = req.Clone(req.Context())
+			req.URL.Scheme, req.URL.Host = serverURL.Scheme, serverURL.Host
+		}
+		return transport.RoundTrip(req)
+	})
+	return WithHTTPClient(context.Background(), client)
+}
+
+// roundTripperFunc turns a function into an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestUpgradeGitHubBranchRule tests that branch upgrades download the file at the new commit.
+func TestUpgradeGitHubBranchRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	const newCommit = "2222222222222222222222222222222222222222"
+	serveHTML := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/branches/main", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commit":{"sha":"` + newCommit + `"}}`))
+	})
+	mux.HandleFunc("/owner/repo/"+newCommit+"/rules/rule.mdc", func(w http.ResponseWriter, r *http.Request) {
+		if serveHTML {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html>not a rule</html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("new content\n"))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	oldContent := []byte("old content\n")
+	if err := os.WriteFile(filepath.Join(cursorDir, "rule.mdc"), oldContent, 0o644); err != nil {
+		t.Fatalf("Failed to write rule: %v", err)
+	}
+
+	rule := &RuleSource{
+		Key:            "rule",
+		SourceType:     SourceTypeGitHubFile,
+		Reference:      "https://github.com/owner/repo/blob/main/rules/rule.mdc",
+		SourceURL:      "https://github.com/owner/repo/blob/main/rules/rule.mdc",
+		GitRef:         "branch=main",
+		ResolvedCommit: "1111111111111111111111111111111111111111",
+		LocalFiles:     []string{"rule.mdc"},
+		ContentSHA256:  calculateSHA256(oldContent),
+	}
+
+	// An HTML page must not be written into the rule, and the commit must not move
+	serveHTML = true
+	if err := upgradeGitHubBranchRule(ctx, cursorDir, rule, rule.GitRef); err == nil {
+		t.Error("Expected error for an HTML response, got nil")
+	}
+	if rule.ResolvedCommit == newCommit {
+		t.Error("ResolvedCommit was updated although the download failed")
+	}
+	data, err := os.ReadFile(filepath.Join(cursorDir, "rule.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read rule: %v", err)
+	}
+	if string(data) != string(oldContent) {
+		t.Errorf("Rule file was modified by a failed upgrade: %q", data)
+	}
+
+	serveHTML = false
+	if err := upgradeGitHubBranchRule(ctx, cursorDir, rule, rule.GitRef); err != nil {
+		t.Fatalf("upgradeGitHubBranchRule returned error: %v", err)
+	}
+	if rule.ResolvedCommit != newCommit {
+		t.Errorf("Expected ResolvedCommit %s, got %s", newCommit, rule.ResolvedCommit)
+	}
+	data, err = os.ReadFile(filepath.Join(cursorDir, "rule.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read rule: %v", err)
+	}
+	if string(data) != "new content\n" {
+		t.Errorf("Expected upgraded content, got %q", data)
+	}
+}
+
+// TestUpgradeGitHubPinnedRule tests that unpinning a file rule moves it to the repository's
+// default branch.
+func TestUpgradeGitHubPinnedRule(t *testing.T) {
+	setPromptPolicy(t, PromptPolicy{Unpin: true}, false)
+	cursorDir := setupTestCursorDir(t)
+
+	const oldCommit = "1111111111111111111111111111111111111111"
+	const newCommit = "2222222222222222222222222222222222222222"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"default_branch":"trunk"}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/branches/trunk", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commit":{"sha":"` + newCommit + `"}}`))
+	})
+	mux.HandleFunc("/owner/repo/"+newCommit+"/rules/rule.mdc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("new content\n"))
+	})
+	ctx := startTestGitHubServer(t, mux)
+
+	oldContent := []byte("old content\n")
+	if err := os.WriteFile(filepath.Join(cursorDir, "rule.mdc"), oldContent, 0o644); err != nil {
+		t.Fatalf("Failed to write rule: %v", err)
+	}
+
+	rule := &RuleSource{
+		Key:            "rule",
+		SourceType:     SourceTypeGitHubFile,
+		Reference:      "https://github.com/owner/repo/blob/" + oldCommit + "/rules/rule.mdc",
+		SourceURL:      "https://github.com/owner/repo/blob/" + oldCommit + "/rules/rule.mdc",
+		GitRef:         "commit=" + oldCommit,
+		ResolvedCommit: oldCommit,
+		LocalFiles:     []string{"rule.mdc"},
+		ContentSHA256:  calculateSHA256(oldContent),
+	}
+
+	if err := upgradeGitHubPinnedRule(ctx, cursorDir, rule, rule.GitRef); err != nil {
+		t.Fatalf("upgradeGitHubPinnedRule returned error: %v", err)
+	}
+	if rule.GitRef != "branch=trunk" {
+		t.Errorf("Expected GitRef branch=trunk, got %s", rule.GitRef)
+	}
+	if rule.ResolvedCommit != newCommit {
+		t.Errorf("Expected ResolvedCommit %s, got %s", newCommit, rule.ResolvedCommit)
+	}
+	data, err := os.ReadFile(filepath.Join(cursorDir, "rule.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read rule: %v", err)
+	}
+	if string(data) != "new content\n" {
+		t.Errorf("Expected upgraded content, got %q", data)
+	}
+}
+
+// TestUpgradeGitHubRangeRule tests that a rule with a version range upgrades within the range
+// while its key and reference stay the same.
+func TestUpgradeGitHubRangeRule(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	tags := `[{"name":"v1.2.0"}]`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/cursor-rules-collection/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(tags))
+	})
+	for _, tag := range []string{"v1.2.0", "v1.3.0", "v2.0.0"} {
+		content := "content at " + tag + "\n"
+		mux.HandleFunc("/owner/cursor-rules-collection/"+tag+"/rule.mdc", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(content))
+		})
+	}
+	ctx := startTestGitHubServer(t, mux)
+
+	rule, err := handleUsernameRuleWithTag(ctx, cursorDir, "owner/rule@^1.2")
+	if err != nil {
+		t.Fatalf("handleUsernameRuleWithTag returned error: %v", err)
+	}
+	if rule.GitRef != "tag=v1.2.0" || rule.VersionRange != "^1.2" {
+		t.Fatalf("Expected tag=v1.2.0 within ^1.2, got %s within %q", rule.GitRef, rule.VersionRange)
+	}
+	key := rule.Key
+	if key != "owner/rule@^1.2" {
+		t.Errorf("Expected the range in the key, got %s", key)
+	}
+
+	// A newer tag within the range and a new major version are released
+	tags = `[{"name":"v1.2.0"},{"name":"v1.3.0"},{"name":"v2.0.0"}]`
+
+	if err := upgradeRule(ctx, cursorDir, &rule); err != nil {
+		t.Fatalf("upgradeRule returned error: %v", err)
+	}
+	if rule.GitRef != "tag=v1.3.0" {
+		t.Errorf("Expected upgrade to stay within ^1.2 and reach v1.3.0, got %s", rule.GitRef)
+	}
+	if rule.Key != key || rule.Reference != "owner/rule@^1.2" || rule.VersionRange != "^1.2" {
+		t.Errorf("Expected key, reference and range to be unchanged, got %s, %s, %s",
+			rule.Key, rule.Reference, rule.VersionRange)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cursorDir, rule.LocalFiles[0]))
+	if err != nil {
+		t.Fatalf("Failed to read rule: %v", err)
+	}
+	if string(data) != "content at v1.3.0\n" {
+		t.Errorf("Expected content of v1.3.0, got %q", data)
+	}
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UserConfigPathEnv overrides the location of the per-user config file.
+const UserConfigPathEnv = "CURSOR_CONFIG_PATH"
+
+// UserConfig holds settings that apply to every project of a user, stored in
+// ~/.cursor-rules/config.json.
+type UserConfig struct {
+	// Username used for username-less references like "rule-name"
+	DefaultUsername string `json:"defaultUsername,omitempty"`
+
+	// Token sent to GitHub when GITHUB_TOKEN is not set
+	GitHubToken string `json:"githubToken,omitempty"`
+
+	// GitHub Enterprise hosts rules can be installed from, in addition to github.com
+	GitHubHosts []GitHubHost `json:"githubHosts,omitempty"`
+
+	// Timeouts, retries, proxy and CA bundle for all downloads
+	HTTP HTTPConfig `json:"http,omitempty"`
+
+	// Limits for downloaded rules
+	Download DownloadConfig `json:"download,omitempty"`
+}
+
+// getUserConfigPath returns the path to the per-user config file.
+func getUserConfigPath() (string, error) {
+	if configPath := os.Getenv(UserConfigPathEnv); configPath != "" {
+		return configPath, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home dir: %w", err)
+	}
+	return filepath.Join(homeDir, ".cursor-rules", "config.json"), nil
+}
+
+// loadUserConfig loads the per-user config, returning an empty config if it doesn't exist.
+func loadUserConfig() (*UserConfig, error) {
+	configPath, err := getUserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Debugf("Config file not found: %s\n", configPath)
+			return &UserConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config UserConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	return &config, nil
+}
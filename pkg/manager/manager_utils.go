@@ -29,6 +29,7 @@ const (
 	SourceTypeGitHubShorthand SourceType = "github-shorthand" // New source type for username/rule pattern
 	SourceTypeGitHubRepoPath  SourceType = "github-repo-path" // New source type for username/repo/path/rule pattern
 	SourceTypeGitHubGlob      SourceType = "github-glob"      // New source type for glob patterns
+	SourceTypeUnmanaged       SourceType = "unmanaged"        // Files adopted from .cursor/rules without a known source
 )
 
 // These are constants for the GitHub action values in rule conflict resolution.
@@ -56,6 +57,10 @@ type RuleSource struct {
 	// e.g. "commit=0609329", "branch=main", etc.
 	GitRef string `json:"gitRef,omitempty"`
 
+	// For username/rule@^1.2 style references, the semver constraint the rule follows;
+	// GitRef holds the tag it currently resolves to
+	VersionRange string `json:"versionRange,omitempty"`
+
 	// The exact file path(s) that ended up in .cursor/rules
 	// If you download multiple .mdc files from a directory, you might store a slice here
 	LocalFiles []string `json:"localFiles"`
@@ -67,13 +72,21 @@ type RuleSource struct {
 	// SHA256 hash of the file content - used to detect local modifications
 	ContentSHA256 string `json:"contentSHA256,omitempty"`
 
+	// For rules with several files (GitHub directories), the SHA256 hash of each
+	// local file; ContentSHA256 is unused for them
+	FileSHA256 map[string]string `json:"fileSHA256,omitempty"`
+
 	// Original glob pattern used (only for glob patterns)
 	GlobPattern string `json:"globPattern,omitempty"`
-}
 
-// Regular expressions for parsing GitHub URLs
-var githubBlobPattern = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/blob/([^/]+)/(.+)$`)
-var githubTreePattern = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/tree/([^/]+)/(.+)$`)
+	// The GitHub blob URL the reference resolved to, so shorthand references
+	// can be fetched again without repeating the lookup
+	SourceURL string `json:"sourceURL,omitempty"`
+
+	// Set when an upgrade merged local edits with upstream changes and left
+	// conflict markers in the file that still need to be resolved
+	MergeConflict bool `json:"mergeConflict,omitempty"`
+}
 
 // Regular expressions for parsing shorthand formats
 var usernameRulePattern = regexp.MustCompile(`^([^/]+)/([^/:@]+)$`)
@@ -96,21 +109,7 @@ func containsRule(rules []RuleSource, key string) bool {
 
 // IsInstalled checks if a rule is already installed.
 func (lock *LockFile) IsInstalled(ruleKey string) bool {
-	// First, check the Rules array (newer format)
-	for _, rule := range lock.Rules {
-		if rule.Key == ruleKey {
-			return true
-		}
-	}
-
-	// For backwards compatibility, also check the Installed array
-	for _, key := range lock.Installed {
-		if key == ruleKey {
-			return true
-		}
-	}
-
-	return false
+	return containsRule(lock.Rules, ruleKey)
 }
 
 // isAbsolutePath checks if a path is absolute.
@@ -118,6 +117,15 @@ func isAbsolutePath(path string) bool {
 	return filepath.IsAbs(path)
 }
 
+// resolveLocalReference returns the path of a local file reference recorded in the lockfile.
+// Relative references are resolved against the project root, not the working directory.
+func resolveLocalReference(cursorDir, ref string) string {
+	if filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(getRootDirectory(cursorDir), ref)
+}
+
 // isRelativePath checks if a path is relative and not a URL.
 func isRelativePath(path string) bool {
 	// If it's a file path with ./ or ../ it's definitely a relative path
@@ -204,14 +212,26 @@ func directoryExists(path string) bool {
 	return info.IsDir()
 }
 
-// isGitHubBlobURL checks if a reference is a GitHub blob URL.
+// isGitHubBlobURL checks if a reference is a blob URL on github.com or a configured host.
 func isGitHubBlobURL(ref string) bool {
-	return githubBlobPattern.MatchString(ref)
+	_, _, _, _, _, ok := parseGitHubURL(ref, "blob")
+	return ok
 }
 
-// isGitHubTreeURL checks if a reference is a GitHub tree URL.
+// isGitHubTreeURL checks if a reference is a tree URL on github.com or a configured host.
 func isGitHubTreeURL(ref string) bool {
-	return githubTreePattern.MatchString(ref)
+	_, _, _, _, _, ok := parseGitHubURL(ref, "tree")
+	return ok
+}
+
+// parseGitHubBlobURL splits a GitHub blob URL into host, owner, repo, git ref and file path.
+func parseGitHubBlobURL(ref string) (host *GitHubHost, owner, repo, gitRef, path string, ok bool) {
+	return parseGitHubURL(ref, "blob")
+}
+
+// parseGitHubTreeURL splits a GitHub tree URL into host, owner, repo, git ref and directory path.
+func parseGitHubTreeURL(ref string) (host *GitHubHost, owner, repo, gitRef, path string, ok bool) {
+	return parseGitHubURL(strings.TrimSuffix(ref, "/"), "tree")
 }
 
 // isUsernameRule checks if a reference matches the username/rule pattern.
@@ -379,41 +399,14 @@ func parseGlobPattern(ref string) (string, string, bool) {
 	return "", ref, true
 }
 
+// getDefaultUsername returns the default username from the user config.
 // Returns empty string if not configured.
 var getDefaultUsername = func() string {
-	// Read from ~/.cursor-rules/config.json or from CURSOR_CONFIG_PATH environment variable
-	configPath := os.Getenv("CURSOR_CONFIG_PATH")
-	if configPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			Debugf("Failed to get user home dir: %v\n", err)
-			return ""
-		}
-		configPath = filepath.Join(homeDir, ".cursor-rules", "config.json")
-	}
-
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		Debugf("Config file not found: %s\n", configPath)
-		return ""
-	}
-
-	// Read config file
-	data, err := os.ReadFile(configPath)
+	config, err := loadUserConfig()
 	if err != nil {
-		Debugf("Failed to read config file: %v\n", err)
+		Debugf("Failed to load config file: %v\n", err)
 		return ""
 	}
-
-	// Parse config
-	var config struct {
-		DefaultUsername string `json:"defaultUsername"`
-	}
-	if err := json.Unmarshal(data, &config); err != nil {
-		Debugf("Failed to parse config file: %v\n", err)
-		return ""
-	}
-
 	return config.DefaultUsername
 }
 
@@ -432,13 +425,16 @@ func generateRuleKey(ref string) string {
 	Debugf("generateRuleKey: input ref='%s'\n", ref)
 
 	// 1) If this is a GitHub blob/tree URL, parse out owner/repo/path
+	if _, owner, repo, _, dir, ok := parseGitHubTreeURL(ref); ok {
+		// A directory's files are installed below its key, e.g. "owner/repo/dir/sub/rule.mdc".
+		// The whole path keeps directories with the same name apart, and the repository keeps
+		// the key apart from username/rule shorthand keys, even for a rules collection
+		key := owner + "/" + repo + "/" + dir
+		Debugf("generateRuleKey: GitHub tree key='%s'\n", key)
+		return key
+	}
 	if isGitHubBlobURL(ref) {
-		matches := githubBlobPattern.FindStringSubmatch(ref)
-		if len(matches) == 5 {
-			owner := matches[1]
-			repo := matches[2]
-			path := matches[4]
-
+		if _, owner, repo, _, path, ok := parseGitHubBlobURL(ref); ok {
 			// Generate a more contextual key with path structure preserved
 			base := filepath.Base(path)
 			ext := filepath.Ext(base)
@@ -470,6 +466,13 @@ func generateRuleKey(ref string) string {
 	}
 	if isUsernameRuleWithTag(ref) {
 		username, rule, tag, _ := parseUsernameRuleWithTag(ref)
+		// A version range resolves to different tags over time, so the key holds the range
+		// itself rather than a tag
+		if isSemverRange(tag) {
+			key := fmt.Sprintf("%s/%s@%s", username, rule, tag)
+			Debugf("generateRuleKey: username/rule@range key='%s'\n", key)
+			return key
+		}
 		// Incorporate the tag into the key
 		key := fmt.Sprintf("%s/%s-%s", username, rule, tag)
 		Debugf("generateRuleKey: username/rule@tag key='%s'\n", key)
@@ -606,6 +609,15 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
+// ruleFilePath returns the absolute path of a rule file recorded in the lockfile.
+// Entries may be relative to .cursor/rules or absolute.
+func ruleFilePath(cursorDir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(cursorDir, file)
+}
+
 // calculateSHA256 calculates the SHA256 hash of a byte slice.
 func calculateSHA256(data []byte) string {
 	hash := sha256.Sum256(data)
@@ -652,30 +664,97 @@ func shortCommit(commit string) string {
 	return commit
 }
 
-// listGitHubRepoFiles lists files in a GitHub repository that match a pattern.
-// Returns a list of paths that match the pattern.
-func listGitHubRepoFiles(ctx context.Context, owner, repo, ref, pattern string) ([]string, error) {
+// listGitHubRepoFiles lists the .mdc files in a GitHub repository that match a pattern.
+// The ref is resolved to a commit first, and the paths are listed at that commit, which
+// is returned so the files can be downloaded from the same snapshot.
+func listGitHubRepoFiles(ctx context.Context, host *GitHubHost, owner, repo, ref, pattern string) (string, []string, error) {
 	// Compile the glob pattern for matching
 	g, err := compileGlob(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid glob pattern: %w", err)
+		return "", nil, fmt.Errorf("invalid glob pattern: %w", err)
+	}
+
+	commit := ref
+	if !isGitCommitHash(ref) {
+		commit, err = getHeadCommitForBranch(ctx, host, owner, repo, ref)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	// One request for the whole tree instead of one per directory
+	entries, truncated, err := listGitHubTree(ctx, host, owner, repo, commit)
+	if err != nil {
+		return "", nil, err
+	}
+	if truncated {
+		// The tree is too large for a single response; walk it directory by directory
+		Debugf("listGitHubRepoFiles: tree of %s/%s at %s is truncated, listing directories\n",
+			owner, repo, shortCommit(commit))
+		files, err := recursivelyListGitHubFiles(ctx, host, owner, repo, commit, "", g, pattern)
+		return commit, files, err
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if entry.Type == "blob" && strings.HasSuffix(entry.Path, ".mdc") && matchGlob(g, entry.Path) {
+			matches = append(matches, entry.Path)
+		}
+	}
+	return commit, matches, nil
+}
+
+// gitTreeEntry is a file ("blob") or directory ("tree") in a Git Trees API response.
+type gitTreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// listGitHubTree returns every entry of a repository's tree at a commit, and whether
+// GitHub truncated the list because the tree is too large.
+func listGitHubTree(ctx context.Context, host *GitHubHost, owner, repo, commit string) ([]gitTreeEntry, bool, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1", host.APIURL, owner, repo, commit)
+
+	req, err := newGitHubRequest(ctx, apiURL)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request for GitHub API: %w", err)
+	}
+	req.Header.Add("Accept", "application/vnd.github.v3+json")
+
+	resp, err := doGitHubRequest(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get repository tree: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	var response struct {
+		SHA       string         `json:"sha"`
+		Tree      []gitTreeEntry `json:"tree"`
+		Truncated bool           `json:"truncated"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, false, fmt.Errorf("failed to parse GitHub API response: %w", err)
 	}
 
-	// Start with the root directory
-	return recursivelyListGitHubFiles(ctx, owner, repo, ref, "", g, pattern)
+	Debugf("listGitHubTree: %d entries in tree %s\n", len(response.Tree), response.SHA)
+	return response.Tree, response.Truncated, nil
 }
 
 // recursivelyListGitHubFiles recursively traverses the GitHub repository structure
 // and finds all files that match the glob pattern.
-func recursivelyListGitHubFiles(ctx context.Context, owner, repo, ref, path string, g glob.Glob, pattern string) ([]string, error) {
+func recursivelyListGitHubFiles(ctx context.Context, host *GitHubHost, owner, repo, ref, path string, g glob.Glob, pattern string) ([]string, error) {
 	// Construct the API URL for the repository contents
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
-	if ref != "" && ref != "main" {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s", host.APIURL, owner, repo, path)
+	if ref != "" {
 		apiURL += fmt.Sprintf("?ref=%s", ref)
 	}
 
 	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	req, err := newGitHubRequest(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for GitHub API: %w", err)
 	}
@@ -684,7 +763,7 @@ func recursivelyListGitHubFiles(ctx context.Context, owner, repo, ref, path stri
 	req.Header.Add("Accept", "application/vnd.github.v3+json")
 
 	// Send the request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doGitHubRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repository contents: %w", err)
 	}
@@ -727,10 +806,10 @@ func recursivelyListGitHubFiles(ctx context.Context, owner, repo, ref, path stri
 	// (check the original pattern string for wildcard characters)
 	if strings.Contains(pattern, "**") || strings.Contains(pattern, "/") {
 		for _, subDir := range subDirs {
-			subMatches, err := recursivelyListGitHubFiles(ctx, owner, repo, ref, subDir, g, pattern)
+			subMatches, err := recursivelyListGitHubFiles(ctx, host, owner, repo, ref, subDir, g, pattern)
 			if err != nil {
 				// Just log errors for subdirectories but don't fail the entire operation
-				fmt.Printf("Warning: Error listing files in %s: %v\n", subDir, err)
+				warnf(ctx, "Error listing files in %s: %v", subDir, err)
 				continue
 			}
 			matches = append(matches, subMatches...)
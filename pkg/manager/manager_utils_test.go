@@ -256,6 +256,11 @@ func TestGenerateRuleKey(t *testing.T) {
 			reference:   "username/rule@v1.2",
 			expectedKey: "username/rule-v1.2", // Adjust expected based on implementation
 		},
+		{
+			name:        "Username/rule with version range",
+			reference:   "username/rule@^1.2",
+			expectedKey: "username/rule@^1.2",
+		},
 		{
 			name:        "Full path with tag",
 			reference:   "username/repo/path/to/rule@v1",
@@ -271,6 +276,16 @@ func TestGenerateRuleKey(t *testing.T) {
 			reference:   "path/to/**/file.mdc",
 			expectedKey: "local/rel/path-to-deep-glob", // Adjust expected based on implementation
 		},
+		{
+			name:        "GitHub tree URL",
+			reference:   "https://github.com/user/repo/tree/main/rules/python/",
+			expectedKey: "user/repo/rules/python",
+		},
+		{
+			name:        "GitHub tree URL in a rules collection",
+			reference:   "https://github.com/user/cursor-rules-collection/tree/main/rules/python",
+			expectedKey: "user/cursor-rules-collection/rules/python",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStatus describes how an installed rule file compares to the lockfile.
+type FileStatus string
+
+// File statuses reported by VerifyRules.
+const (
+	FileStatusOK         FileStatus = "ok"         // content matches the recorded hash
+	FileStatusUnverified FileStatus = "unverified" // present, but no hash was recorded
+	FileStatusModified   FileStatus = "modified"   // content differs from the recorded hash
+	FileStatusMissing    FileStatus = "missing"    // listed in the lockfile but not on disk
+	FileStatusUntracked  FileStatus = "untracked"  // on disk but not listed in the lockfile
+)
+
+// FileVerification is the verification result for a single rule file.
+type FileVerification struct {
+	// Rule that owns the file (empty for untracked files)
+	RuleKey string `json:"ruleKey,omitempty"`
+
+	// Path relative to .cursor/rules
+	Path string `json:"path"`
+
+	Status FileStatus `json:"status"`
+
+	// Whether the lockfile records a content hash for the file
+	HasHash bool `json:"hasHash"`
+
+	ExpectedSHA256 string `json:"expectedSHA256,omitempty"`
+	ActualSHA256   string `json:"actualSHA256,omitempty"`
+}
+
+// VerifyReport is the result of checking .cursor/rules against the lockfile.
+type VerifyReport struct {
+	Files []FileVerification `json:"files"`
+}
+
+// HasDrift reports whether any file is modified, missing or untracked.
+func (r *VerifyReport) HasDrift() bool {
+	return r.Count(FileStatusModified)+r.Count(FileStatusMissing)+r.Count(FileStatusUntracked) > 0
+}
+
+// Count returns the number of files with the given status.
+func (r *VerifyReport) Count(status FileStatus) int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// scanRuleFiles returns every .mdc file under .cursor/rules, relative to it and slash-separated.
+func scanRuleFiles(cursorDir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(cursorDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".mdc") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(cursorDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// verifyRuleFile checks a single file of a rule against the recorded hash.
+func verifyRuleFile(cursorDir string, rule RuleSource, file string) (FileVerification, error) {
+	result := FileVerification{
+		RuleKey:        rule.Key,
+		Path:           relativeRuleFile(cursorDir, rule.Key, file),
+		HasHash:        rule.ContentSHA256 != "",
+		ExpectedSHA256: rule.ContentSHA256,
+	}
+
+	actual, err := fileContentSHA256(ruleFilePath(cursorDir, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			result.Status = FileStatusMissing
+			return result, nil
+		}
+		return result, &ErrLocalFileAccess{Path: file, Cause: err}
+	}
+	result.ActualSHA256 = actual
+
+	switch {
+	case !result.HasHash:
+		result.Status = FileStatusUnverified
+	case actual != rule.ContentSHA256:
+		result.Status = FileStatusModified
+	default:
+		result.Status = FileStatusOK
+	}
+	return result, nil
+}
+
+// VerifyRules checks every file recorded in the lockfile against its content hash
+// and reports .mdc files that the lockfile doesn't know about.
+func VerifyRules(cursorDir string) (*VerifyReport, error) {
+	lock, err := LoadLockFile(cursorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	report := &VerifyReport{Files: []FileVerification{}}
+	tracked := make(map[string]bool)
+
+	for _, lockedRule := range lock.Rules {
+		// Each file of a directory rule has its own hash
+		for _, rule := range ruleFileSources(lockedRule) {
+			for _, file := range rule.LocalFiles {
+				result, err := verifyRuleFile(cursorDir, rule, file)
+				if err != nil {
+					return nil, err
+				}
+				tracked[result.Path] = true
+				report.Files = append(report.Files, result)
+			}
+		}
+	}
+
+	onDisk, err := scanRuleFiles(cursorDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range onDisk {
+		if !tracked[file] {
+			report.Files = append(report.Files, FileVerification{
+				Path:   file,
+				Status: FileStatusUntracked,
+			})
+		}
+	}
+
+	return report, nil
+}
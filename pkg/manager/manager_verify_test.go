@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestVerifyRules tests that VerifyRules classifies installed files.
+func TestVerifyRules(t *testing.T) {
+	cursorDir := setupTestCursorDir(t)
+
+	files := map[string]string{
+		"clean.mdc":     "clean",
+		"modified.mdc":  "changed locally",
+		"nohash.mdc":    "no hash",
+		"untracked.mdc": "stray",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cursorDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	lock := &LockFile{Rules: []RuleSource{
+		{Key: "clean", LocalFiles: []string{"clean.mdc"}, ContentSHA256: calculateSHA256([]byte("clean"))},
+		{Key: "modified", LocalFiles: []string{"modified.mdc"}, ContentSHA256: calculateSHA256([]byte("original"))},
+		{Key: "nohash", LocalFiles: []string{"nohash.mdc"}},
+		{Key: "missing", LocalFiles: []string{"missing.mdc"}, ContentSHA256: calculateSHA256([]byte("gone"))},
+	}}
+	if err := lock.Save(cursorDir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	report, err := VerifyRules(cursorDir)
+	if err != nil {
+		t.Fatalf("VerifyRules returned error: %v", err)
+	}
+
+	expected := map[string]FileStatus{
+		"clean.mdc":     FileStatusOK,
+		"modified.mdc":  FileStatusModified,
+		"nohash.mdc":    FileStatusUnverified,
+		"missing.mdc":   FileStatusMissing,
+		"untracked.mdc": FileStatusUntracked,
+	}
+	if len(report.Files) != len(expected) {
+		t.Fatalf("Expected %d files in report, got %+v", len(expected), report.Files)
+	}
+	for _, f := range report.Files {
+		if expected[f.Path] != f.Status {
+			t.Errorf("Expected %s to be %s, got %s", f.Path, expected[f.Path], f.Status)
+		}
+	}
+
+	if !report.HasDrift() {
+		t.Error("Expected report to have drift")
+	}
+}